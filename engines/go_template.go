@@ -15,3 +15,14 @@ func (t *GoTemplateEngine) Render(ctx context.Context, tplName string, data any)
 	err := t.T.ExecuteTemplate(bs, tplName, data)
 	return bs.Bytes(), err
 }
+
+// URLForFuncs 返回模板中反向生成 URL 的 urlFor 函数, 需要在解析模板之前注册, 例如:
+//
+//	tpl := template.New("default").Funcs(engines.URLForFuncs(serv.URLFor))
+//
+// 模板中使用: {{ urlFor "user-fav" "id" .ID "fid" .FavID }}
+func URLForFuncs(urlFor func(name string, params ...any) (string, error)) template.FuncMap {
+	return template.FuncMap{
+		"urlFor": urlFor,
+	}
+}
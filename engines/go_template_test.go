@@ -0,0 +1,57 @@
+package engines
+
+import (
+	"context"
+	"html/template"
+	"jungle/server"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+// go test -v engines/*.go -run TestGoTemplateEngine_URLFor
+func TestGoTemplateEngine_URLFor(t *testing.T) {
+
+	serv := server.New(":8081")
+	serv.Get("/user/:id/fav/:fid", func(ctx *server.Context) {}).Name("user-fav")
+	tpl, err := template.New("default").Funcs(URLForFuncs(serv.URLFor)).Parse(
+		`{{ define "fav" }}<a href="{{ urlFor "user-fav" "id" .ID "fid" .FavID }}">fav</a>{{ end }}` +
+			`{{ define "missing" }}<a href="{{ urlFor "user-fav" "id" .ID }}">fav</a>{{ end }}`)
+	require.NoError(t, err)
+	eg := &GoTemplateEngine{
+		T: tpl,
+	}
+
+	testCases := []struct {
+		name    string
+		tplName string
+		data    any
+		wantRes string
+		wantErr error
+	}{
+		{
+			name:    "url for",
+			tplName: "fav",
+			data:    map[string]any{"ID": 1, "FavID": "a b"},
+			wantRes: `<a href="/user/1/fav/a%20b">fav</a>`,
+		},
+		{
+			name:    "missing param",
+			tplName: "missing",
+			data:    map[string]any{"ID": 1},
+			wantErr: server.ErrMissingURLParam,
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			res, err := eg.Render(context.Background(), tc.tplName, tc.data)
+			if tc.wantErr != nil {
+				require.ErrorIs(t, err, tc.wantErr)
+				require.ErrorContains(t, err, "fid of route user-fav")
+				return
+			}
+			require.NoError(t, err)
+			require.Equal(t, tc.wantRes, string(res))
+		})
+	}
+}
@@ -36,10 +36,8 @@ func (m *Middleware) Build() func(ctx *server.Context) {
 		spanCtx, span := m.tracer.Start(reqCtx, "unknown")
 		defer func() {
 			span.SetName(ctx.MatchedPath)
-			status, exists := ctx.Get("status")
-			if statusCode, ok := status.(int); exists && ok {
-				span.SetAttributes(attribute.Int("http.status", statusCode))
-			}
+			span.SetAttributes(attribute.Int("http.status", ctx.Status()))
+			span.SetAttributes(attribute.Int("http.response_size", ctx.Size()))
 			data, exists := ctx.Get("data")
 			if dataStr, ok := data.(string); exists && ok {
 				span.SetAttributes(attribute.String("resp.data", dataStr))
@@ -113,13 +113,7 @@ func (m *Middleware) Build() func(ctx *server.Context) {
 			}
 			elapsed := float64(time.Since(startTime).Milliseconds())
 
-			var statusCode string
-			status, exists := ctx.Get("status")
-			if sts, ok := status.(int); exists && ok {
-				statusCode = strconv.Itoa(sts)
-			} else {
-				statusCode = "unknown"
-			}
+			statusCode := strconv.Itoa(ctx.Status())
 
 			// counterVec
 			counterVec.WithLabelValues(
@@ -1,6 +1,7 @@
 package session_test
 
 import (
+	"context"
 	"github.com/google/uuid"
 	"github.com/stretchr/testify/require"
 	sessionmiddleware "jungle/middlewares/session"
@@ -124,7 +125,7 @@ func TestMiddleware_Session(t *testing.T) {
 		t.Fatal(err)
 	}
 
-	err = serv.ShutDown()
+	err = serv.Shutdown(context.Background())
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -0,0 +1,446 @@
+package server
+
+import (
+	"encoding"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"net/url"
+	"reflect"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// 参数来源, 同时也是结构体的 tag 名
+const (
+	sourcePath   = "path"
+	sourceQuery  = "query"
+	sourceHeader = "header"
+	sourceForm   = "form"
+)
+
+var bindSources = []string{sourcePath, sourceQuery, sourceHeader, sourceForm}
+
+var (
+	timeType            = reflect.TypeOf(time.Time{})
+	durationType        = reflect.TypeOf(time.Duration(0))
+	textUnmarshalerType = reflect.TypeOf((*encoding.TextUnmarshaler)(nil)).Elem()
+)
+
+// BindError 参数绑定失败, 可以通过 errors.Is 判断底层的错误
+type BindError struct {
+	// Field 结构体字段名, 嵌套的结构体用 . 连接
+	Field string
+	// Source 参数来源: path, query, header, form, default
+	Source string
+	Key    string
+	Value  string
+	Err    error
+}
+
+func (e *BindError) Error() string {
+	return fmt.Sprintf("bind %s %q=%q to field %s: %v", e.Source, e.Key, e.Value, e.Field, e.Err)
+}
+
+func (e *BindError) Unwrap() error {
+	return e.Err
+}
+
+// bindField 一个可以绑定的字段
+type bindField struct {
+	index []int
+	name  string
+	// label 校验错误中的字段名
+	label string
+	rules []validationTag
+	// keys 每个来源对应的参数名, 没有 tag 时为空
+	keys map[string]string
+	// jsonKey 兼容 BindQuery 等方法, 没有对应的 tag 时使用 json tag
+	jsonKey    string
+	defaultVal string
+	hasDefault bool
+	timeFormat string
+}
+
+// bindFieldsCache 缓存结构体的字段信息, key 为 reflect.Type
+var bindFieldsCache sync.Map
+
+func cachedBindFields(typ reflect.Type) []*bindField {
+	if fields, ok := bindFieldsCache.Load(typ); ok {
+		return fields.([]*bindField)
+	}
+	fields, _ := bindFieldsCache.LoadOrStore(typ, parseBindFields(typ, nil, "", ""))
+	return fields.([]*bindField)
+}
+
+func parseBindFields(typ reflect.Type, index []int, prefix string, labelPrefix string) []*bindField {
+	var fields []*bindField
+	for i := 0; i < typ.NumField(); i++ {
+		sf := typ.Field(i)
+		if !sf.IsExported() && !sf.Anonymous {
+			continue
+		}
+		field := &bindField{
+			index:      append(append([]int{}, index...), i),
+			name:       prefix + sf.Name,
+			keys:       make(map[string]string),
+			timeFormat: sf.Tag.Get("time_format"),
+			rules:      parseValidationTag(sf.Tag.Get("validate")),
+		}
+		for _, source := range bindSources {
+			if key := tagName(sf.Tag.Get(source)); key != "" {
+				field.keys[source] = key
+			}
+		}
+		field.defaultVal, field.hasDefault = sf.Tag.Lookup("default")
+		jsonKey := tagName(sf.Tag.Get("json"))
+		if jsonKey == "" && sf.Tag.Get("json") != "-" {
+			jsonKey = sf.Name
+		}
+
+		// 没有 tag 的结构体字段继续解析里面的字段, 指针不展开, 避免循环引用
+		if len(field.keys) == 0 && !field.hasDefault && len(field.rules) == 0 && isNestedStruct(sf.Type) {
+			fieldPrefix, fieldLabelPrefix := prefix+sf.Name+".", labelPrefix+firstNonEmpty(jsonKey, sf.Name)+"."
+			if sf.Anonymous {
+				fieldPrefix, fieldLabelPrefix = prefix, labelPrefix
+			}
+			fields = append(fields, parseBindFields(sf.Type, field.index, fieldPrefix, fieldLabelPrefix)...)
+			continue
+		}
+		if !sf.IsExported() {
+			continue
+		}
+		field.jsonKey = jsonKey
+		label := jsonKey
+		for _, source := range bindSources {
+			if key, ok := field.keys[source]; ok {
+				label = key
+				break
+			}
+		}
+		field.label = labelPrefix + firstNonEmpty(label, sf.Name)
+		fields = append(fields, field)
+	}
+	return fields
+}
+
+// tagName 去掉 tag 中逗号之后的选项, - 表示忽略
+func tagName(tag string) string {
+	name, _, _ := strings.Cut(tag, ",")
+	if name == "-" {
+		return ""
+	}
+	return name
+}
+
+func firstNonEmpty(vals ...string) string {
+	for _, val := range vals {
+		if val != "" {
+			return val
+		}
+	}
+	return ""
+}
+
+func isNestedStruct(typ reflect.Type) bool {
+	if typ.Kind() != reflect.Struct || typ == timeType {
+		return false
+	}
+	return !reflect.PointerTo(typ).Implements(textUnmarshalerType)
+}
+
+// Bind 根据结构体的 tag 绑定请求参数
+// 支持 path, query, header 和 form tag, 请求体按 Content-Type 选择 Codec 解码, 例如 JSON 使用 json tag
+// default tag 设置没有传参时的默认值, 切片的默认值用逗号分隔
+// time.Time 默认按 RFC3339 解析, 可以用 time_format tag 指定格式, 或者 unix, unixmilli, unixmicro
+// 绑定之后按 validate tag 校验, 校验失败时返回 ValidationErrors, 可选的字段需要加上 omitempty
+func (ctx *Context) Bind(val any) error {
+	rv, err := bindTarget(val)
+	if err != nil {
+		return err
+	}
+	fields := cachedBindFields(rv.Type())
+
+	// 先设置默认值, 请求体和其他参数再覆盖默认值
+	for _, field := range fields {
+		if !field.hasDefault {
+			continue
+		}
+		vals := []string{field.defaultVal}
+		if fv := rv.FieldByIndex(field.index); fv.Kind() == reflect.Slice {
+			vals = strings.Split(field.defaultVal, ",")
+		}
+		if err = field.set(rv, "default", "", vals); err != nil {
+			return err
+		}
+	}
+
+	if hasBody(ctx.Req) {
+		if codec, ok := ctx.getCodecs().decoder(ctx.Req.Header.Get("Content-Type")); ok {
+			if err = codec.Decode(ctx.Req, val); err != nil && !errors.Is(err, io.EOF) {
+				return err
+			}
+		}
+	}
+
+	for _, source := range bindSources {
+		var values func(key string) []string
+		for _, field := range fields {
+			key, ok := field.keys[source]
+			if !ok {
+				continue
+			}
+			// 需要的时候才读取参数, 比如没有 form tag 时不解析表单
+			if values == nil {
+				if values, err = ctx.bindValues(source); err != nil {
+					return err
+				}
+			}
+			if vals := values(key); len(vals) > 0 {
+				if err = field.set(rv, source, key, vals); err != nil {
+					return err
+				}
+			}
+		}
+	}
+
+	return ctx.getValidator().validate(rv, fields)
+}
+
+// MustBind 绑定失败时中断调用链并返回错误响应, 返回值表示是否绑定成功
+// 校验失败时使用 WithValidationFormatter 设置的格式, 其他错误返回 400
+func (ctx *Context) MustBind(val any) bool {
+	err := ctx.Bind(val)
+	if err == nil {
+		return true
+	}
+	var errs ValidationErrors
+	if errors.As(err, &errs) {
+		status, body := ctx.getValidator().formatter(errs)
+		ctx.AbortJSON(status, body)
+		return false
+	}
+	ctx.AbortJSON(http.StatusBadRequest, map[string]string{"error": err.Error()})
+	return false
+}
+
+func (ctx *Context) getCodecs() *codecRegistry {
+	if ctx.codecs == nil {
+		return defaultCodecs
+	}
+	return ctx.codecs
+}
+
+func (ctx *Context) getValidator() *validator {
+	if ctx.validator == nil {
+		return defaultValidator
+	}
+	return ctx.validator
+}
+
+// bindBySource BindQuery, BindForm 和 BindHeader 只绑定一种来源的参数, 没有对应的 tag 时使用 json tag
+func (ctx *Context) bindBySource(val any, source string) error {
+	rv, err := bindTarget(val)
+	if err != nil {
+		return err
+	}
+	values, err := ctx.bindValues(source)
+	if err != nil {
+		return err
+	}
+	return bindWithFallback(rv, source, values)
+}
+
+func bindWithFallback(rv reflect.Value, source string, values func(key string) []string) error {
+	for _, field := range cachedBindFields(rv.Type()) {
+		key, ok := field.keys[source]
+		if !ok {
+			key = field.jsonKey
+		}
+		if key == "" {
+			continue
+		}
+		if vals := values(key); len(vals) > 0 {
+			if err := field.set(rv, source, key, vals); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func bindTarget(val any) (reflect.Value, error) {
+	rv := reflect.ValueOf(val)
+	if rv.Kind() != reflect.Pointer || rv.IsNil() || rv.Elem().Kind() != reflect.Struct {
+		return reflect.Value{}, ErrInvalidBindTarget
+	}
+	return rv.Elem(), nil
+}
+
+// bindValues 返回按参数名获取参数值的函数
+func (ctx *Context) bindValues(source string) (func(key string) []string, error) {
+	switch source {
+	case sourcePath:
+		return valuesOf(ctx.PathParams), nil
+	case sourceQuery:
+		if len(ctx.QueryParams) == 0 {
+			ctx.QueryParams = ctx.Req.URL.Query()
+		}
+		return valuesOf(ctx.QueryParams), nil
+	case sourceHeader:
+		return ctx.Req.Header.Values, nil
+	case sourceForm:
+		if err := parseForm(ctx.Req); err != nil {
+			return nil, err
+		}
+		return valuesOf(ctx.Req.Form), nil
+	}
+	return nil, fmt.Errorf("unknown bind source: %s", source)
+}
+
+func valuesOf(values url.Values) func(key string) []string {
+	return func(key string) []string {
+		return values[key]
+	}
+}
+
+// parseForm multipart 表单同样需要解析
+func parseForm(req *http.Request) error {
+	mediaType, _, _ := mime.ParseMediaType(req.Header.Get("Content-Type"))
+	if mediaType == "multipart/form-data" {
+		return req.ParseMultipartForm(32 << 20)
+	}
+	return req.ParseForm()
+}
+
+func hasBody(req *http.Request) bool {
+	return req.Body != nil && req.Body != http.NoBody && req.ContentLength != 0
+}
+
+func (field *bindField) set(rv reflect.Value, source string, key string, vals []string) error {
+	fv := rv.FieldByIndex(field.index)
+	if err := setValues(fv, vals, field.timeFormat); err != nil {
+		return &BindError{
+			Field:  field.name,
+			Source: source,
+			Key:    key,
+			Value:  strings.Join(vals, ","),
+			Err:    err,
+		}
+	}
+	return nil
+}
+
+// setValues 切片使用全部的参数值, 其他类型只使用第一个
+func setValues(fv reflect.Value, vals []string, timeFormat string) error {
+	if fv.Kind() == reflect.Slice && fv.Type().Elem().Kind() != reflect.Uint8 &&
+		!reflect.PointerTo(fv.Type()).Implements(textUnmarshalerType) {
+		slice := reflect.MakeSlice(fv.Type(), len(vals), len(vals))
+		for i, val := range vals {
+			if err := setValue(slice.Index(i), val, timeFormat); err != nil {
+				return err
+			}
+		}
+		fv.Set(slice)
+		return nil
+	}
+	return setValue(fv, vals[0], timeFormat)
+}
+
+func setValue(fv reflect.Value, val string, timeFormat string) error {
+	if fv.Kind() == reflect.Pointer {
+		elem := reflect.New(fv.Type().Elem())
+		if err := setValue(elem.Elem(), val, timeFormat); err != nil {
+			return err
+		}
+		fv.Set(elem)
+		return nil
+	}
+	// 空字符串当作没有传参, 保留原来的值
+	if val == "" && fv.Kind() != reflect.String {
+		return nil
+	}
+
+	result := &Result{val: val}
+	switch fv.Type() {
+	case timeType:
+		t, err := parseTime(result, timeFormat)
+		if err != nil {
+			return err
+		}
+		fv.Set(reflect.ValueOf(t))
+		return nil
+	case durationType:
+		d, err := time.ParseDuration(val)
+		if err != nil {
+			return err
+		}
+		fv.SetInt(int64(d))
+		return nil
+	}
+	if unmarshaler, ok := fv.Addr().Interface().(encoding.TextUnmarshaler); ok {
+		return unmarshaler.UnmarshalText([]byte(val))
+	}
+
+	switch fv.Kind() {
+	case reflect.String:
+		fv.SetString(val)
+	case reflect.Bool:
+		b, err := result.Bool()
+		if err != nil {
+			return err
+		}
+		fv.SetBool(b)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		n, err := result.Int64()
+		if err != nil {
+			return err
+		}
+		if fv.OverflowInt(n) {
+			return &strconv.NumError{Func: "ParseInt", Num: val, Err: strconv.ErrRange}
+		}
+		fv.SetInt(n)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		n, err := result.UInt64()
+		if err != nil {
+			return err
+		}
+		if fv.OverflowUint(n) {
+			return &strconv.NumError{Func: "ParseUint", Num: val, Err: strconv.ErrRange}
+		}
+		fv.SetUint(n)
+	case reflect.Float32, reflect.Float64:
+		f, err := result.Float64()
+		if err != nil {
+			return err
+		}
+		if fv.OverflowFloat(f) {
+			return &strconv.NumError{Func: "ParseFloat", Num: val, Err: strconv.ErrRange}
+		}
+		fv.SetFloat(f)
+	case reflect.Slice:
+		// []byte
+		fv.SetBytes([]byte(val))
+	default:
+		return fmt.Errorf("unsupported type: %s", fv.Type())
+	}
+	return nil
+}
+
+func parseTime(result *Result, timeFormat string) (time.Time, error) {
+	switch timeFormat {
+	case "":
+		return result.Time(time.RFC3339)
+	case "unix":
+		return result.TimeFromUnix()
+	case "unixmilli":
+		return result.TimeFromUnixMilli()
+	case "unixmicro":
+		return result.TimeFromUnixMicro()
+	}
+	return result.Time(timeFormat)
+}
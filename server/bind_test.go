@@ -0,0 +1,222 @@
+package server
+
+import (
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+type bindPage struct {
+	Page int `query:"page" default:"1"`
+	Size int `query:"size" default:"20"`
+}
+
+type bindUserReq struct {
+	bindPage
+	ID      uint64         `path:"id"`
+	Token   string         `header:"X-Token"`
+	Tags    []string       `query:"tag"`
+	Scores  []int          `query:"score"`
+	Active  *bool          `query:"active"`
+	Ratio   float32        `query:"ratio"`
+	Since   time.Time      `query:"since" time_format:"2006-01-02"`
+	Expire  time.Time      `query:"expire" time_format:"unix"`
+	Timeout time.Duration  `query:"timeout" default:"5s"`
+	IP      net.IP         `header:"X-Real-IP"`
+	Sort    []string       `query:"sort" default:"id,name"`
+	Name    string         `form:"name" json:"name"`
+	Age     int            `json:"age"`
+	Filter  bindUserFilter `json:"-"`
+}
+
+type bindUserFilter struct {
+	Status string `query:"status" default:"active"`
+}
+
+// go test -v server/*.go -run TestContext_Bind
+func TestContext_Bind(t *testing.T) {
+	active := true
+	testCases := []struct {
+		name    string
+		req     func() *http.Request
+		want    bindUserReq
+		wantErr string
+	}{
+		{
+			name: "defaults",
+			req: func() *http.Request {
+				return httptest.NewRequest(http.MethodGet, "/user/1", nil)
+			},
+			want: bindUserReq{
+				bindPage: bindPage{Page: 1, Size: 20},
+				ID:       1,
+				Timeout:  time.Second * 5,
+				Sort:     []string{"id", "name"},
+				Filter:   bindUserFilter{Status: "active"},
+			},
+		},
+		{
+			name: "query and header",
+			req: func() *http.Request {
+				req := httptest.NewRequest(http.MethodGet, "/user/2?page=3&tag=a&tag=b&score=1&score=2&active=true"+
+					"&ratio=0.5&since=2024-05-01&expire=1714521600&timeout=1m&sort=age&status=banned&size=", nil)
+				req.Header.Set("X-Token", "secret")
+				req.Header.Set("X-Real-IP", "10.0.0.1")
+				return req
+			},
+			want: bindUserReq{
+				bindPage: bindPage{Page: 3, Size: 20},
+				ID:       2,
+				Token:    "secret",
+				Tags:     []string{"a", "b"},
+				Scores:   []int{1, 2},
+				Active:   &active,
+				Ratio:    0.5,
+				Since:    time.Date(2024, 5, 1, 0, 0, 0, 0, time.UTC),
+				Expire:   time.Unix(1714521600, 0),
+				Timeout:  time.Minute,
+				IP:       net.ParseIP("10.0.0.1"),
+				Sort:     []string{"age"},
+				Filter:   bindUserFilter{Status: "banned"},
+			},
+		},
+		{
+			name: "json body",
+			req: func() *http.Request {
+				req := httptest.NewRequest(http.MethodPost, "/user/3?page=2", strings.NewReader(`{"name":"jungle","age":18}`))
+				req.Header.Set("Content-Type", "application/json; charset=utf-8")
+				return req
+			},
+			want: bindUserReq{
+				bindPage: bindPage{Page: 2, Size: 20},
+				ID:       3,
+				Name:     "jungle",
+				Age:      18,
+				Timeout:  time.Second * 5,
+				Sort:     []string{"id", "name"},
+				Filter:   bindUserFilter{Status: "active"},
+			},
+		},
+		{
+			name: "form body",
+			req: func() *http.Request {
+				form := url.Values{"name": []string{"jungle"}}
+				req := httptest.NewRequest(http.MethodPost, "/user/4", strings.NewReader(form.Encode()))
+				req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+				return req
+			},
+			want: bindUserReq{
+				bindPage: bindPage{Page: 1, Size: 20},
+				ID:       4,
+				Name:     "jungle",
+				Timeout:  time.Second * 5,
+				Sort:     []string{"id", "name"},
+				Filter:   bindUserFilter{Status: "active"},
+			},
+		},
+		{
+			name: "invalid int",
+			req: func() *http.Request {
+				return httptest.NewRequest(http.MethodGet, "/user/5?page=first", nil)
+			},
+			wantErr: `bind query "page"="first" to field Page: strconv.ParseInt: parsing "first": invalid syntax`,
+		},
+		{
+			name: "negative uint",
+			req: func() *http.Request {
+				return httptest.NewRequest(http.MethodGet, "/user/-1", nil)
+			},
+			wantErr: `bind path "id"="-1" to field ID: strconv.ParseUint: parsing "-1": invalid syntax`,
+		},
+		{
+			name: "float overflow",
+			req: func() *http.Request {
+				return httptest.NewRequest(http.MethodGet, "/user/6?ratio=1e40", nil)
+			},
+			wantErr: `bind query "ratio"="1e40" to field Ratio: strconv.ParseFloat: parsing "1e40": value out of range`,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			var got bindUserReq
+			var err error
+			serv := New(":8081")
+			handler := func(ctx *Context) {
+				err = ctx.Bind(&got)
+			}
+			serv.Get("/user/:id", handler)
+			serv.Post("/user/:id", handler)
+			serv.ServeHTTP(httptest.NewRecorder(), tc.req())
+			if tc.wantErr != "" {
+				require.EqualError(t, err, tc.wantErr)
+				var bindErr *BindError
+				require.ErrorAs(t, err, &bindErr)
+				return
+			}
+			require.NoError(t, err)
+			require.True(t, tc.want.Since.Equal(got.Since))
+			require.True(t, tc.want.Expire.Equal(got.Expire))
+			tc.want.Since, tc.want.Expire = got.Since, got.Expire
+			require.Equal(t, tc.want, got)
+		})
+	}
+}
+
+// go test -v server/*.go -run TestContext_BindInvalidTarget
+func TestContext_BindInvalidTarget(t *testing.T) {
+	ctx := NewContext(httptest.NewRequest(http.MethodGet, "/", nil), httptest.NewRecorder(), nil)
+	var req bindPage
+	var nilReq *bindPage
+	var page int
+	require.ErrorIs(t, ctx.Bind(req), ErrInvalidBindTarget)
+	require.ErrorIs(t, ctx.Bind(nilReq), ErrInvalidBindTarget)
+	require.ErrorIs(t, ctx.Bind(&page), ErrInvalidBindTarget)
+	require.ErrorIs(t, ctx.BindQuery(nil), ErrInvalidBindTarget)
+}
+
+// go test -v server/*.go -run TestContext_BindBySource
+func TestContext_BindBySource(t *testing.T) {
+	type legacyReq struct {
+		Page  int      `json:"page"`
+		Tags  []string `json:"tag"`
+		Token string   `header:"X-Token"`
+		Name  string   `json:"name"`
+	}
+
+	req := httptest.NewRequest(http.MethodPost, "/?page=2&tag=a&tag=b", strings.NewReader("name=jungle"))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("X-Token", "secret")
+	ctx := NewContext(req, httptest.NewRecorder(), nil)
+
+	var query legacyReq
+	require.NoError(t, ctx.BindQuery(&query))
+	require.Equal(t, legacyReq{Page: 2, Tags: []string{"a", "b"}}, query)
+
+	var header legacyReq
+	require.NoError(t, ctx.BindHeader(&header))
+	require.Equal(t, legacyReq{Token: "secret"}, header)
+
+	// 表单包含 query 参数
+	var form legacyReq
+	require.NoError(t, ctx.BindForm(&form))
+	require.Equal(t, legacyReq{Page: 2, Tags: []string{"a", "b"}, Name: "jungle"}, form)
+}
+
+// go test -v server/*.go -run TestResult_Error
+func TestResult_Error(t *testing.T) {
+	ctx := NewContext(httptest.NewRequest(http.MethodGet, "/?page=1", nil), httptest.NewRecorder(), nil)
+	_, err := ctx.QueryValue("size").Int()
+	require.EqualError(t, err, "key: size not exists")
+	_, err = ctx.QueryValue("size").TimeFromUnix()
+	require.EqualError(t, err, "key: size not exists")
+	page, err := ctx.QueryValue("page").Int()
+	require.NoError(t, err)
+	require.Equal(t, 1, page)
+}
@@ -0,0 +1,368 @@
+package server
+
+import (
+	"encoding/json"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"net/url"
+	"reflect"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/vmihailenco/msgpack/v5"
+	"google.golang.org/protobuf/proto"
+)
+
+// Codec 按媒体类型编解码请求体和响应体
+// 只能把响应编码成部分媒体类型时, 可以再实现 EncodeMediaTypes() []string
+type Codec interface {
+	// MediaTypes 支持的媒体类型, 第一个用作响应的 Content-Type
+	MediaTypes() []string
+	// Decode 解码请求体到 val
+	Decode(req *http.Request, val any) error
+	// Encode 编码 val 到响应体, val 的类型不支持时返回 ErrUnsupportedValue
+	Encode(w io.Writer, val any) error
+}
+
+// encodeMediaTyper Codec 只能把响应编码成部分媒体类型时实现, 没有实现时 MediaTypes 都可以用于响应
+type encodeMediaTyper interface {
+	EncodeMediaTypes() []string
+}
+
+// JSONCodec application/json, 也用于 application/problem+json 这样以 +json 结尾的类型
+type JSONCodec struct{}
+
+func (JSONCodec) MediaTypes() []string {
+	return []string{"application/json"}
+}
+
+func (JSONCodec) Decode(req *http.Request, val any) error {
+	return json.NewDecoder(req.Body).Decode(val)
+}
+
+func (JSONCodec) Encode(w io.Writer, val any) error {
+	data, err := json.Marshal(val)
+	if err != nil {
+		return err
+	}
+	_, err = w.Write(data)
+	return err
+}
+
+// XMLCodec application/xml 和 text/xml, 也用于以 +xml 结尾的类型
+type XMLCodec struct{}
+
+func (XMLCodec) MediaTypes() []string {
+	return []string{"application/xml", "text/xml"}
+}
+
+func (XMLCodec) Decode(req *http.Request, val any) error {
+	return xml.NewDecoder(req.Body).Decode(val)
+}
+
+// Encode map 等 XML 不支持的类型返回 ErrUnsupportedValue
+func (XMLCodec) Encode(w io.Writer, val any) error {
+	err := xml.NewEncoder(w).Encode(val)
+	var unsupported *xml.UnsupportedTypeError
+	if errors.As(err, &unsupported) {
+		return fmt.Errorf("%w: %v", ErrUnsupportedValue, err)
+	}
+	return err
+}
+
+// FormCodec 表单, 按 form tag 绑定, 没有 form tag 时使用 json tag
+type FormCodec struct{}
+
+func (FormCodec) MediaTypes() []string {
+	return []string{"application/x-www-form-urlencoded", "multipart/form-data"}
+}
+
+// EncodeMediaTypes multipart/form-data 需要 boundary, Encode 只支持 application/x-www-form-urlencoded
+func (FormCodec) EncodeMediaTypes() []string {
+	return []string{"application/x-www-form-urlencoded"}
+}
+
+func (FormCodec) Decode(req *http.Request, val any) error {
+	rv, err := bindTarget(val)
+	if err != nil {
+		return err
+	}
+	if err = parseForm(req); err != nil {
+		return err
+	}
+	return bindWithFallback(rv, sourceForm, valuesOf(req.Form))
+}
+
+// Encode 支持 url.Values, map[string]string 和结构体
+func (FormCodec) Encode(w io.Writer, val any) error {
+	values := url.Values{}
+	switch v := val.(type) {
+	case url.Values:
+		values = v
+	case map[string][]string:
+		values = v
+	case map[string]string:
+		for key, s := range v {
+			values.Set(key, s)
+		}
+	default:
+		rv := reflect.ValueOf(val)
+		for rv.Kind() == reflect.Pointer && !rv.IsNil() {
+			rv = rv.Elem()
+		}
+		if rv.Kind() != reflect.Struct {
+			return fmt.Errorf("%w: %T", ErrUnsupportedValue, val)
+		}
+		for _, field := range cachedBindFields(rv.Type()) {
+			// 表单只有一层, 嵌套的结构体不展开
+			if strings.Contains(field.name, ".") {
+				continue
+			}
+			key, ok := field.keys[sourceForm]
+			if !ok {
+				key = field.jsonKey
+			}
+			if key == "" {
+				continue
+			}
+			fv := rv.FieldByIndex(field.index)
+			if fv.Kind() == reflect.Slice && fv.Type().Elem().Kind() != reflect.Uint8 {
+				for i := 0; i < fv.Len(); i++ {
+					values.Add(key, fmt.Sprint(fv.Index(i).Interface()))
+				}
+				continue
+			}
+			values.Set(key, fmt.Sprint(fv.Interface()))
+		}
+	}
+	_, err := io.WriteString(w, values.Encode())
+	return err
+}
+
+// MsgpackCodec MessagePack, 没有 msgpack tag 时使用 json tag
+type MsgpackCodec struct{}
+
+func (MsgpackCodec) MediaTypes() []string {
+	return []string{"application/msgpack", "application/x-msgpack", "application/vnd.msgpack"}
+}
+
+func (MsgpackCodec) Decode(req *http.Request, val any) error {
+	dec := msgpack.NewDecoder(req.Body)
+	dec.SetCustomStructTag("json")
+	return dec.Decode(val)
+}
+
+func (MsgpackCodec) Encode(w io.Writer, val any) error {
+	enc := msgpack.NewEncoder(w)
+	enc.SetCustomStructTag("json")
+	return enc.Encode(val)
+}
+
+// ProtobufCodec protobuf, val 必须是 proto.Message
+type ProtobufCodec struct{}
+
+func (ProtobufCodec) MediaTypes() []string {
+	return []string{"application/x-protobuf", "application/protobuf"}
+}
+
+func (ProtobufCodec) Decode(req *http.Request, val any) error {
+	msg, ok := val.(proto.Message)
+	if !ok {
+		return fmt.Errorf("%w: %T", ErrUnsupportedValue, val)
+	}
+	data, err := io.ReadAll(req.Body)
+	if err != nil {
+		return err
+	}
+	// Bind 在解码之前设置了默认值, 不能被清空
+	return proto.UnmarshalOptions{Merge: true}.Unmarshal(data, msg)
+}
+
+func (ProtobufCodec) Encode(w io.Writer, val any) error {
+	msg, ok := val.(proto.Message)
+	if !ok {
+		return fmt.Errorf("%w: %T", ErrUnsupportedValue, val)
+	}
+	data, err := proto.Marshal(msg)
+	if err != nil {
+		return err
+	}
+	_, err = w.Write(data)
+	return err
+}
+
+// codecRegistry 按注册顺序保存 Codec, Accept 没有偏好时优先使用先注册的
+type codecRegistry struct {
+	mu          sync.RWMutex
+	entries     []*codecEntry
+	byMediaType map[string]Codec
+}
+
+// codecEntry mediaTypes 为这个 Codec 仍然负责的媒体类型, 被后注册的 Codec 覆盖的类型会被移除
+// encodeTypes 为其中可以用于响应的媒体类型
+type codecEntry struct {
+	codec       Codec
+	mediaTypes  []string
+	encodeTypes []string
+}
+
+// defaultCodecs 不通过 HTTPServer 创建的 ctx 使用内置的 Codec
+var defaultCodecs = newCodecRegistry()
+
+func newCodecRegistry() *codecRegistry {
+	r := &codecRegistry{byMediaType: make(map[string]Codec)}
+	for _, codec := range []Codec{JSONCodec{}, XMLCodec{}, FormCodec{}, MsgpackCodec{}, ProtobufCodec{}} {
+		r.register(codec)
+	}
+	return r
+}
+
+// register 媒体类型和已注册的 Codec 相同时, 这些类型改由新的 Codec 处理
+func (r *codecRegistry) register(codec Codec) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	entry := &codecEntry{codec: codec}
+	for _, mediaType := range codec.MediaTypes() {
+		entry.mediaTypes = append(entry.mediaTypes, strings.ToLower(mediaType))
+	}
+	entry.encodeTypes = slices.Clone(entry.mediaTypes)
+	if enc, ok := codec.(encodeMediaTyper); ok {
+		entry.encodeTypes = nil
+		for _, mediaType := range enc.EncodeMediaTypes() {
+			if mediaType = strings.ToLower(mediaType); slices.Contains(entry.mediaTypes, mediaType) {
+				entry.encodeTypes = append(entry.encodeTypes, mediaType)
+			}
+		}
+	}
+	entries := make([]*codecEntry, 0, len(r.entries)+1)
+	for _, old := range r.entries {
+		overridden := func(mediaType string) bool {
+			return slices.Contains(entry.mediaTypes, mediaType)
+		}
+		old.mediaTypes = slices.DeleteFunc(old.mediaTypes, overridden)
+		old.encodeTypes = slices.DeleteFunc(old.encodeTypes, overridden)
+		if len(old.mediaTypes) > 0 {
+			entries = append(entries, old)
+		}
+	}
+	r.entries = append(entries, entry)
+	for _, mediaType := range entry.mediaTypes {
+		r.byMediaType[mediaType] = codec
+	}
+}
+
+// decoder 根据请求的 Content-Type 选择 Codec
+func (r *codecRegistry) decoder(contentType string) (Codec, bool) {
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return nil, false
+	}
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	if codec, ok := r.byMediaType[mediaType]; ok {
+		return codec, true
+	}
+	switch {
+	case strings.HasSuffix(mediaType, "+json"):
+		codec, ok := r.byMediaType["application/json"]
+		return codec, ok
+	case strings.HasSuffix(mediaType, "+xml"):
+		codec, ok := r.byMediaType["application/xml"]
+		return codec, ok
+	}
+	return nil, false
+}
+
+// acceptRange Accept 中的一项
+type acceptRange struct {
+	mediaRange string
+	q          float64
+}
+
+func parseAccept(accept string) []acceptRange {
+	var ranges []acceptRange
+	for _, item := range strings.Split(accept, ",") {
+		mediaRange, params, err := mime.ParseMediaType(strings.TrimSpace(item))
+		if err != nil {
+			continue
+		}
+		q := 1.0
+		if s, ok := params["q"]; ok {
+			if q, err = strconv.ParseFloat(s, 64); err != nil {
+				continue
+			}
+		}
+		ranges = append(ranges, acceptRange{mediaRange: mediaRange, q: q})
+	}
+	return ranges
+}
+
+// quality 返回 mediaType 的权重, 多个范围都包含 mediaType 时使用最具体的那个
+func quality(ranges []acceptRange, mediaType string) float64 {
+	q, specificity := 0.0, -1
+	for _, r := range ranges {
+		if !matchMediaRange(r.mediaRange, mediaType) {
+			continue
+		}
+		s := 0
+		if r.mediaRange == mediaType {
+			s = 2
+		} else if r.mediaRange != "*/*" {
+			s = 1
+		}
+		if s > specificity {
+			q, specificity = r.q, s
+		}
+	}
+	return q
+}
+
+// negotiate 按 Accept 的权重返回可用的 Codec, 权重相同时按注册顺序, 没有 Accept 时返回全部
+func (r *codecRegistry) negotiate(accept string) []codecChoice {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	ranges := parseAccept(accept)
+	choices := make([]codecChoice, 0, len(r.entries))
+	for _, entry := range r.entries {
+		if len(entry.encodeTypes) == 0 {
+			continue
+		}
+		if accept == "" {
+			choices = append(choices, codecChoice{codec: entry.codec, mediaType: entry.encodeTypes[0], q: 1})
+			continue
+		}
+		best := codecChoice{codec: entry.codec}
+		for _, mediaType := range entry.encodeTypes {
+			if q := quality(ranges, mediaType); q > best.q {
+				best.mediaType, best.q = mediaType, q
+			}
+		}
+		if best.q > 0 {
+			choices = append(choices, best)
+		}
+	}
+	sort.SliceStable(choices, func(i, j int) bool {
+		return choices[i].q > choices[j].q
+	})
+	return choices
+}
+
+// codecChoice mediaType 为客户端接受的类型, 用作响应的 Content-Type
+type codecChoice struct {
+	codec     Codec
+	mediaType string
+	q         float64
+}
+
+// RegisterCodec 注册 Codec, 媒体类型和已注册的 Codec 相同时, 这些类型改由新的 Codec 处理
+// 内置 JSON, XML, 表单, MessagePack 和 protobuf
+func (s *HTTPServer) RegisterCodec(codec Codec) {
+	s.codecs.register(codec)
+}
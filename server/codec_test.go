@@ -0,0 +1,350 @@
+package server
+
+import (
+	"bytes"
+	"encoding/xml"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"github.com/vmihailenco/msgpack/v5"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/wrapperspb"
+)
+
+type codecUser struct {
+	XMLName xml.Name `json:"-" xml:"user" msgpack:"-"`
+	ID      int      `path:"id" json:"-" xml:"-"`
+	Name    string   `json:"name" xml:"name" form:"name"`
+	Tags    []string `json:"tags" xml:"tag"`
+	Role    string   `json:"role" xml:"role" default:"guest"`
+}
+
+// go test -v server/*.go -run TestContext_BindCodec
+func TestContext_BindCodec(t *testing.T) {
+	msgpackBody, err := msgpack.Marshal(map[string]any{"name": "jungle", "tags": []string{"a", "b"}})
+	require.NoError(t, err)
+
+	testCases := []struct {
+		name        string
+		contentType string
+		body        []byte
+		want        codecUser
+	}{
+		{
+			name:        "json",
+			contentType: "application/json",
+			body:        []byte(`{"name":"jungle","tags":["a","b"],"role":"admin"}`),
+			want:        codecUser{ID: 1, Name: "jungle", Tags: []string{"a", "b"}, Role: "admin"},
+		},
+		{
+			name:        "json suffix",
+			contentType: "application/merge-patch+json",
+			body:        []byte(`{"name":"jungle"}`),
+			want:        codecUser{ID: 1, Name: "jungle", Role: "guest"},
+		},
+		{
+			name:        "xml",
+			contentType: "text/xml; charset=utf-8",
+			body:        []byte(`<user><name>jungle</name><tag>a</tag><tag>b</tag></user>`),
+			want:        codecUser{XMLName: xml.Name{Local: "user"}, ID: 1, Name: "jungle", Tags: []string{"a", "b"}, Role: "guest"},
+		},
+		{
+			name:        "form",
+			contentType: "application/x-www-form-urlencoded",
+			body:        []byte("name=jungle&tags=a&tags=b"),
+			want:        codecUser{ID: 1, Name: "jungle", Tags: []string{"a", "b"}, Role: "guest"},
+		},
+		{
+			name:        "msgpack",
+			contentType: "application/msgpack",
+			body:        msgpackBody,
+			want:        codecUser{ID: 1, Name: "jungle", Tags: []string{"a", "b"}, Role: "guest"},
+		},
+		{
+			name:        "unknown content type",
+			contentType: "application/octet-stream",
+			body:        []byte("jungle"),
+			want:        codecUser{ID: 1, Role: "guest"},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			var got codecUser
+			var err error
+			serv := New(":8081")
+			serv.Post("/user/:id", func(ctx *Context) {
+				err = ctx.Bind(&got)
+			})
+			req := httptest.NewRequest(http.MethodPost, "/user/1", bytes.NewReader(tc.body))
+			req.Header.Set("Content-Type", tc.contentType)
+			serv.ServeHTTP(httptest.NewRecorder(), req)
+			require.NoError(t, err)
+			require.Equal(t, tc.want, got)
+		})
+	}
+}
+
+// go test -v server/*.go -run TestContext_BindProtobuf
+func TestContext_BindProtobuf(t *testing.T) {
+	body, err := proto.Marshal(wrapperspb.String("jungle"))
+	require.NoError(t, err)
+	req := httptest.NewRequest(http.MethodPost, "/", bytes.NewReader(body))
+	req.Header.Set("Content-Type", "application/x-protobuf")
+	ctx := NewContext(req, httptest.NewRecorder(), nil)
+
+	var msg wrapperspb.StringValue
+	require.NoError(t, ctx.Bind(&msg))
+	require.Equal(t, "jungle", msg.GetValue())
+
+	// 不是 proto.Message 时无法解码
+	req = httptest.NewRequest(http.MethodPost, "/", bytes.NewReader(body))
+	req.Header.Set("Content-Type", "application/x-protobuf")
+	ctx = NewContext(req, httptest.NewRecorder(), nil)
+	require.ErrorIs(t, ctx.Bind(&codecUser{}), ErrUnsupportedValue)
+}
+
+// go test -v server/*.go -run TestContext_Negotiate
+func TestContext_Negotiate(t *testing.T) {
+	user := &codecUser{Name: "jungle", Tags: []string{"a"}, Role: "admin"}
+	// 用结构体编码, map 的 key 顺序是随机的
+	var msgpackBody bytes.Buffer
+	enc := msgpack.NewEncoder(&msgpackBody)
+	enc.SetCustomStructTag("json")
+	require.NoError(t, enc.Encode(user))
+	protoBody, err := proto.Marshal(wrapperspb.String("jungle"))
+	require.NoError(t, err)
+
+	testCases := []struct {
+		name            string
+		val             any
+		accept          string
+		wantCode        int
+		wantContentType string
+		wantBody        string
+	}{
+		{
+			name:            "no accept",
+			val:             user,
+			wantCode:        http.StatusOK,
+			wantContentType: "application/json",
+			wantBody:        `{"name":"jungle","tags":["a"],"role":"admin"}`,
+		},
+		{
+			name:            "wildcard",
+			val:             user,
+			accept:          "*/*",
+			wantCode:        http.StatusOK,
+			wantContentType: "application/json",
+			wantBody:        `{"name":"jungle","tags":["a"],"role":"admin"}`,
+		},
+		{
+			name:            "browser",
+			val:             user,
+			accept:          "text/html,application/xhtml+xml,application/xml;q=0.9,*/*;q=0.8",
+			wantCode:        http.StatusOK,
+			wantContentType: "application/xml",
+			wantBody:        `<user><name>jungle</name><tag>a</tag><role>admin</role></user>`,
+		},
+		{
+			name:            "text xml",
+			val:             user,
+			accept:          "text/xml",
+			wantCode:        http.StatusOK,
+			wantContentType: "text/xml",
+			wantBody:        `<user><name>jungle</name><tag>a</tag><role>admin</role></user>`,
+		},
+		{
+			name:            "msgpack",
+			val:             user,
+			accept:          "application/x-msgpack",
+			wantCode:        http.StatusOK,
+			wantContentType: "application/x-msgpack",
+			wantBody:        msgpackBody.String(),
+		},
+		{
+			name:            "form",
+			val:             user,
+			accept:          "application/x-www-form-urlencoded",
+			wantCode:        http.StatusOK,
+			wantContentType: "application/x-www-form-urlencoded",
+			wantBody:        "name=jungle&role=admin&tags=a",
+		},
+		{
+			// multipart/form-data 只用于解码请求体
+			name:            "multipart not used for response",
+			val:             user,
+			accept:          "multipart/form-data, application/json;q=0.5",
+			wantCode:        http.StatusOK,
+			wantContentType: "application/json",
+			wantBody:        `{"name":"jungle","tags":["a"],"role":"admin"}`,
+		},
+		{
+			name:            "only multipart accepted",
+			val:             user,
+			accept:          "multipart/form-data",
+			wantCode:        http.StatusNotAcceptable,
+			wantContentType: "",
+			wantBody:        "NOT ACCEPTABLE",
+		},
+		{
+			name:            "protobuf",
+			val:             wrapperspb.String("jungle"),
+			accept:          "application/x-protobuf",
+			wantCode:        http.StatusOK,
+			wantContentType: "application/x-protobuf",
+			wantBody:        string(protoBody),
+		},
+		{
+			name:            "fallback when value not supported",
+			val:             user,
+			accept:          "application/x-protobuf, application/json;q=0.5",
+			wantCode:        http.StatusOK,
+			wantContentType: "application/json",
+			wantBody:        `{"name":"jungle","tags":["a"],"role":"admin"}`,
+		},
+		{
+			name:            "excluded by q=0",
+			val:             user,
+			accept:          "application/json;q=0, */*",
+			wantCode:        http.StatusOK,
+			wantContentType: "application/xml",
+			wantBody:        `<user><name>jungle</name><tag>a</tag><role>admin</role></user>`,
+		},
+		{
+			name:            "xml falls back for map",
+			val:             map[string]any{"name": "jungle"},
+			accept:          "application/xml, application/json;q=0.5",
+			wantCode:        http.StatusOK,
+			wantContentType: "application/json",
+			wantBody:        `{"name":"jungle"}`,
+		},
+		{
+			name:            "map only xml accepted",
+			val:             map[string]any{"name": "jungle"},
+			accept:          "application/xml",
+			wantCode:        http.StatusNotAcceptable,
+			wantContentType: "",
+			wantBody:        "NOT ACCEPTABLE",
+		},
+		{
+			name:            "encode error",
+			val:             map[string]any{"ch": make(chan int)},
+			accept:          "application/json",
+			wantCode:        http.StatusInternalServerError,
+			wantContentType: "",
+			wantBody:        "INTERNAL SERVER ERROR",
+		},
+		{
+			name:            "not acceptable",
+			val:             user,
+			accept:          "image/png",
+			wantCode:        http.StatusNotAcceptable,
+			wantContentType: "",
+			wantBody:        "NOT ACCEPTABLE",
+		},
+		{
+			name:            "no codec supports value",
+			val:             user,
+			accept:          "application/protobuf",
+			wantCode:        http.StatusNotAcceptable,
+			wantContentType: "",
+			wantBody:        "NOT ACCEPTABLE",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			serv := New(":8081")
+			serv.Get("/user", func(ctx *Context) {
+				ctx.Negotiate(http.StatusOK, tc.val)
+			})
+			req := httptest.NewRequest(http.MethodGet, "/user", nil)
+			if tc.accept != "" {
+				req.Header.Set("Accept", tc.accept)
+			}
+			resp := httptest.NewRecorder()
+			serv.ServeHTTP(resp, req)
+			require.Equal(t, tc.wantCode, resp.Code)
+			require.Equal(t, tc.wantContentType, resp.Header().Get("Content-Type"))
+			require.Equal(t, "Accept", resp.Header().Get("Vary"))
+			require.Equal(t, tc.wantBody, resp.Body.String())
+		})
+	}
+}
+
+// plainCodec 把响应写成纯文本, 用于测试替换内置的 Codec
+type plainCodec struct{}
+
+func (plainCodec) MediaTypes() []string {
+	return []string{"application/json", "text/plain"}
+}
+
+func (plainCodec) Decode(req *http.Request, val any) error {
+	data, err := io.ReadAll(req.Body)
+	if err != nil {
+		return err
+	}
+	val.(*codecUser).Name = string(data)
+	return nil
+}
+
+func (plainCodec) Encode(w io.Writer, val any) error {
+	_, err := io.WriteString(w, val.(*codecUser).Name)
+	return err
+}
+
+// go test -v server/*.go -run TestHTTPServer_RegisterCodec
+func TestHTTPServer_RegisterCodec(t *testing.T) {
+	serv := New(":8081")
+	serv.RegisterCodec(plainCodec{})
+	serv.Post("/user", func(ctx *Context) {
+		var user codecUser
+		require.NoError(t, ctx.Bind(&user))
+		ctx.Negotiate(http.StatusCreated, &user)
+	})
+
+	req := httptest.NewRequest(http.MethodPost, "/user", strings.NewReader("jungle"))
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Accept", "application/json")
+	resp := httptest.NewRecorder()
+	serv.ServeHTTP(resp, req)
+	require.Equal(t, http.StatusCreated, resp.Code)
+	require.Equal(t, "application/json", resp.Header().Get("Content-Type"))
+	require.Equal(t, "jungle", resp.Body.String())
+
+	// JSON 的媒体类型都被替换, 没有 Accept 时优先使用先注册的 XML
+	req = httptest.NewRequest(http.MethodPost, "/user", strings.NewReader("jungle"))
+	req.Header.Set("Content-Type", "text/plain")
+	resp = httptest.NewRecorder()
+	serv.ServeHTTP(resp, req)
+	require.Equal(t, "application/xml", resp.Header().Get("Content-Type"))
+
+	// 只替换了部分媒体类型的 Codec 继续处理剩下的类型
+	serv.RegisterCodec(textXMLCodec{})
+	req = httptest.NewRequest(http.MethodPost, "/user", strings.NewReader("<user><name>jungle</name></user>"))
+	req.Header.Set("Content-Type", "application/xml")
+	req.Header.Set("Accept", "text/xml, application/xml;q=0.5")
+	resp = httptest.NewRecorder()
+	serv.ServeHTTP(resp, req)
+	require.Equal(t, "text/xml", resp.Header().Get("Content-Type"))
+	require.Equal(t, "<name>jungle</name>", resp.Body.String())
+}
+
+// textXMLCodec 只处理 text/xml
+type textXMLCodec struct {
+	plainCodec
+}
+
+func (textXMLCodec) MediaTypes() []string {
+	return []string{"text/xml"}
+}
+
+func (textXMLCodec) Encode(w io.Writer, val any) error {
+	_, err := io.WriteString(w, "<name>"+val.(*codecUser).Name+"</name>")
+	return err
+}
@@ -1,9 +1,11 @@
 package server
 
 import (
+	"bytes"
 	"encoding/json"
 	"errors"
 	"fmt"
+	"log"
 	"math"
 	"mime/multipart"
 	"net/http"
@@ -11,6 +13,7 @@ import (
 	"net/url"
 	"strconv"
 	"sync"
+	"sync/atomic"
 	"time"
 )
 
@@ -31,18 +34,141 @@ type Context struct {
 	values map[string]any // 存储值
 
 	tplEngine TemplateEngine
+	// validator Bind 使用的校验规则, 为 nil 时只有内置规则
+	validator *validator
+	// codecs Bind 和 Negotiate 使用的编解码, 为 nil 时只有内置的 Codec
+	codecs *codecRegistry
+
+	// 复用的 map, PathParams 等字段被替换之后, reset 时恢复成这些 map
+	pathParams   url.Values
+	queryParams  url.Values
+	headerParams url.Values
+	// Resp 默认指向 writer, 用来记录状态码和响应的大小
+	writer responseWriter
+	// 请求结束, ctx 已经放回 pool 并且还没有被复用
+	released atomic.Bool
 }
 
 func NewContext(req *http.Request, resp http.ResponseWriter, tplEngine TemplateEngine) *Context {
-	return &Context{
-		Req:          req,
-		Resp:         resp,
+	ctx := &Context{
 		values:       make(map[string]any),
 		HandlerChain: make([]HandleFunc, 0),
-		PathParams:   make(url.Values),
-		QueryParams:  make(url.Values),
-		HeaderParams: make(url.Values),
-		tplEngine:    tplEngine,
+		pathParams:   make(url.Values),
+		queryParams:  make(url.Values),
+		headerParams: make(url.Values),
+	}
+	ctx.reset(req, resp, tplEngine)
+	return ctx
+}
+
+// reset 清空上一个请求留下的数据, 复用已经分配的 map
+func (ctx *Context) reset(req *http.Request, resp http.ResponseWriter, tplEngine TemplateEngine) {
+	ctx.Req = req
+	ctx.writer.reset(resp)
+	ctx.Resp = &ctx.writer
+	ctx.MatchedPath = ""
+	// PathParams 可能是路由匹配时新建的, QueryParams 和 HeaderParams 可能直接引用了请求中的数据
+	// 所以只清空 ctx 自己的 map, 不修改外部的数据
+	clear(ctx.pathParams)
+	clear(ctx.queryParams)
+	clear(ctx.headerParams)
+	ctx.PathParams = ctx.pathParams
+	ctx.QueryParams = ctx.queryParams
+	ctx.HeaderParams = ctx.headerParams
+	ctx.HandlerChain = ctx.HandlerChain[:0]
+	ctx.Index = 0
+	clear(ctx.values)
+	ctx.tplEngine = tplEngine
+	ctx.released.Store(false)
+}
+
+// Copy 复制一个不会被回收的 ctx, 需要在 handler 返回之后继续使用 ctx 时 (例如在 goroutine 中), 必须使用 Copy
+// 复制的 ctx 不能再调用 Next, 也不应该再写响应
+func (ctx *Context) Copy() *Context {
+	ctx.checkReleased()
+	cp := NewContext(ctx.Req, ctx.writer.ResponseWriter, ctx.tplEngine)
+	cp.writer.status = ctx.writer.status
+	cp.writer.size = ctx.writer.size
+	cp.writer.written = ctx.writer.written
+	cp.MatchedPath = ctx.MatchedPath
+	cp.validator = ctx.validator
+	cp.codecs = ctx.codecs
+	for key, vals := range ctx.PathParams {
+		cp.PathParams[key] = append([]string(nil), vals...)
+	}
+	for key, vals := range ctx.QueryParams {
+		cp.QueryParams[key] = append([]string(nil), vals...)
+	}
+	for key, vals := range ctx.HeaderParams {
+		cp.HeaderParams[key] = append([]string(nil), vals...)
+	}
+	ctx.mux.RLock()
+	for key, val := range ctx.values {
+		cp.values[key] = val
+	}
+	ctx.mux.RUnlock()
+	cp.Index = MAX_INDEX
+	return cp
+}
+
+// Status 响应的状态码, 还没有写响应时返回 200
+func (ctx *Context) Status() int {
+	return ctx.writer.Status()
+}
+
+// Size 已经写入的响应体的字节数
+func (ctx *Context) Size() int {
+	return ctx.writer.Size()
+}
+
+// Written 响应头是否已经发送
+func (ctx *Context) Written() bool {
+	return ctx.writer.Written()
+}
+
+// BufferResponse 之后写入的状态码和响应体先缓冲起来, 调用链执行完之后再一次性发送
+// 中间件在 ctx.Next() 之前调用, 就可以在之后通过 SetStatus, ResponseBody, SetResponseBody 和 Resp.Header() 改写响应
+// 响应头已经发送时不生效
+func (ctx *Context) BufferResponse() {
+	if !ctx.writer.written {
+		ctx.writer.buffered = true
+	}
+}
+
+// ResponseBody 缓冲的响应体, 没有开启缓冲时返回 nil
+func (ctx *Context) ResponseBody() []byte {
+	if !ctx.writer.buffered {
+		return nil
+	}
+	return ctx.writer.buf.Bytes()
+}
+
+// SetResponseBody 替换缓冲的响应体, 没有开启缓冲时不生效
+func (ctx *Context) SetResponseBody(data []byte) {
+	if !ctx.writer.buffered {
+		return
+	}
+	ctx.writer.buf.Reset()
+	ctx.writer.buf.Write(data)
+	ctx.writer.size = len(data)
+	ctx.writer.headerSet = true
+}
+
+// SetStatus 设置状态码, 开启缓冲时可以覆盖 handler 已经写入的状态码
+func (ctx *Context) SetStatus(status int) {
+	if ctx.writer.buffered {
+		ctx.writer.status = status
+		ctx.writer.headerSet = true
+		return
+	}
+	ctx.Resp.WriteHeader(status)
+}
+
+// checkReleased ctx 放回 pool 之后, 被下一个请求复用之前继续使用时 panic
+// 复用之后无法再区分, 需要在请求结束之后使用 ctx 时必须 Copy
+func (ctx *Context) checkReleased() {
+	if ctx.released.Load() {
+		panic(ErrContextReleased)
 	}
 }
 
@@ -59,56 +185,19 @@ func (ctx *Context) BindJSON(val any) error {
 	return decoder.Decode(val)
 }
 
+// BindForm 只绑定 form tag 的字段, 没有 form tag 时使用 json tag
 func (ctx *Context) BindForm(val any) error {
-	err := ctx.Req.ParseForm()
-	if err != nil {
-		return err
-	}
-	var data map[string]any = map[string]any{}
-	for k, v := range ctx.Req.Form {
-		if len(v) > 0 {
-			data[k] = v[0]
-		}
-	}
-	dataBytes, err := json.Marshal(data)
-	if err != nil {
-		return err
-	}
-	return json.Unmarshal(dataBytes, val)
+	return ctx.bindBySource(val, sourceForm)
 }
 
+// BindQuery 只绑定 query tag 的字段, 没有 query tag 时使用 json tag
 func (ctx *Context) BindQuery(val any) error {
-	if len(ctx.QueryParams) == 0 {
-		ctx.QueryParams = ctx.Req.URL.Query()
-	}
-	var data map[string]any = map[string]any{}
-	for k, v := range ctx.QueryParams {
-		if len(v) > 0 {
-			data[k] = v[0]
-		}
-	}
-	dataBytes, err := json.Marshal(data)
-	if err != nil {
-		return err
-	}
-	return json.Unmarshal(dataBytes, val)
+	return ctx.bindBySource(val, sourceQuery)
 }
 
+// BindHeader 只绑定 header tag 的字段, 没有 header tag 时使用 json tag
 func (ctx *Context) BindHeader(val any) error {
-	if len(ctx.HeaderParams) == 0 {
-		ctx.HeaderParams = url.Values(ctx.Req.Header)
-	}
-	var data map[string]any = map[string]any{}
-	for k, v := range ctx.HeaderParams {
-		if len(v) > 0 {
-			data[k] = v[0]
-		}
-	}
-	dataBytes, err := json.Marshal(data)
-	if err != nil {
-		return err
-	}
-	return json.Unmarshal(dataBytes, val)
+	return ctx.bindBySource(val, sourceHeader)
 }
 
 func (ctx *Context) FormValue(key string) (result *Result) {
@@ -174,56 +263,56 @@ type Result struct {
 
 func (result *Result) Int() (val int, err error) {
 	if result.err != nil {
-		return 0, err
+		return 0, result.err
 	}
 	return strconv.Atoi(result.val)
 }
 
 func (result *Result) Int64() (val int64, err error) {
 	if result.err != nil {
-		return 0, err
+		return 0, result.err
 	}
 	return strconv.ParseInt(result.val, 10, 64)
 }
 
 func (result *Result) UInt64() (val uint64, err error) {
 	if result.err != nil {
-		return 0, err
+		return 0, result.err
 	}
 	return strconv.ParseUint(result.val, 10, 64)
 }
 
 func (result *Result) Float64() (val float64, err error) {
 	if result.err != nil {
-		return 0, err
+		return 0, result.err
 	}
 	return strconv.ParseFloat(result.val, 64)
 }
 
 func (result *Result) Bool() (val bool, err error) {
 	if result.err != nil {
-		return false, err
+		return false, result.err
 	}
 	return strconv.ParseBool(result.val)
 }
 
 func (result *Result) Time(layout string) (val time.Time, err error) {
 	if result.err != nil {
-		return time.Time{}, err
+		return time.Time{}, result.err
 	}
 	return time.Parse(layout, result.val)
 }
 
 func (result *Result) TimeInLocation(layout string, loc *time.Location) (val time.Time, err error) {
 	if result.err != nil {
-		return time.Time{}, err
+		return time.Time{}, result.err
 	}
 	return time.ParseInLocation(layout, result.val, loc)
 }
 
 func (result *Result) TimeFromUnix() (val time.Time, err error) {
 	if result.err != nil {
-		return time.Time{}, err
+		return time.Time{}, result.err
 	}
 	secs, err := result.Int64()
 	if err != nil {
@@ -234,7 +323,7 @@ func (result *Result) TimeFromUnix() (val time.Time, err error) {
 
 func (result *Result) TimeFromUnixMilli() (val time.Time, err error) {
 	if result.err != nil {
-		return time.Time{}, err
+		return time.Time{}, result.err
 	}
 	msecs, err := result.Int64()
 	if err != nil {
@@ -245,7 +334,7 @@ func (result *Result) TimeFromUnixMilli() (val time.Time, err error) {
 
 func (result *Result) TimeFromUnixMicro() (val time.Time, err error) {
 	if result.err != nil {
-		return time.Time{}, err
+		return time.Time{}, result.err
 	}
 	usecs, err := result.Int64()
 	if err != nil {
@@ -256,23 +345,24 @@ func (result *Result) TimeFromUnixMicro() (val time.Time, err error) {
 
 // response
 func (ctx *Context) JSON(status int, val any) {
+	ctx.checkReleased()
 	data, err := json.Marshal(val)
 	if err != nil {
 		panic(err)
 	}
 
-	// status
-	ctx.Resp.WriteHeader(status)
-
 	// 用来做trace的时候用的
 	ctx.Set("status", status)
 	ctx.Set("data", string(data))
 
-	// header
+	// header 需要在 WriteHeader 之前设置, 否则不会发送
 	contentLength := len(data)
 	ctx.Resp.Header().Set("Content-Type", "application/json")
 	ctx.Resp.Header().Set("Content-Length", strconv.Itoa(contentLength))
 
+	// status
+	ctx.Resp.WriteHeader(status)
+
 	// body
 	writenLength, err := ctx.Resp.Write(data)
 	if err != nil {
@@ -283,12 +373,46 @@ func (ctx *Context) JSON(status int, val any) {
 	}
 }
 
+// Negotiate 根据 Accept 请求头选择 Codec 编码响应, 没有 Accept 时使用 JSON
+// 编码失败时尝试客户端接受的下一个 Codec, 都不支持 val 时返回 406, 其他编码错误返回 500
+func (ctx *Context) Negotiate(status int, val any) {
+	ctx.checkReleased()
+	var buf bytes.Buffer
+	var encodeErr error
+	for _, choice := range ctx.getCodecs().negotiate(ctx.Req.Header.Get("Accept")) {
+		buf.Reset()
+		if err := choice.codec.Encode(&buf, val); err != nil {
+			if !errors.Is(err, ErrUnsupportedValue) {
+				encodeErr = err
+			}
+			continue
+		}
+
+		header := ctx.Resp.Header()
+		header.Add("Vary", "Accept")
+		header.Set("Content-Type", choice.mediaType)
+		header.Set("Content-Length", strconv.Itoa(buf.Len()))
+		ctx.Resp.WriteHeader(status)
+		_, _ = ctx.Resp.Write(buf.Bytes())
+		return
+	}
+	ctx.Resp.Header().Add("Vary", "Accept")
+	if encodeErr != nil {
+		log.Println(encodeErr)
+		ctx.WriteString(http.StatusInternalServerError, []byte("INTERNAL SERVER ERROR"))
+		return
+	}
+	ctx.WriteString(http.StatusNotAcceptable, []byte("NOT ACCEPTABLE"))
+}
+
 func (ctx *Context) WriteString(code int, msg []byte) {
+	ctx.checkReleased()
 	ctx.Resp.WriteHeader(code)
 	_, _ = ctx.Resp.Write(msg)
 }
 
 func (ctx *Context) Next() {
+	ctx.checkReleased()
 	ctx.Index++
 	for ctx.Index < len(ctx.HandlerChain) {
 		ctx.HandlerChain[ctx.Index](ctx)
@@ -334,6 +458,7 @@ func (ctx *Context) SetCookie(ck *http.Cookie) {
 // values
 
 func (ctx *Context) Get(key string) (val any, exists bool) {
+	ctx.checkReleased()
 	ctx.mux.RLock()
 	defer ctx.mux.RUnlock()
 	val, exists = ctx.values[key]
@@ -341,12 +466,14 @@ func (ctx *Context) Get(key string) (val any, exists bool) {
 }
 
 func (ctx *Context) Set(key string, val any) {
+	ctx.checkReleased()
 	ctx.mux.Lock()
 	defer ctx.mux.Unlock()
 	ctx.values[key] = val
 }
 
 func (ctx *Context) Del(key string) {
+	ctx.checkReleased()
 	ctx.mux.Lock()
 	defer ctx.mux.Unlock()
 	delete(ctx.values, key)
@@ -0,0 +1,142 @@
+package server
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+// go test -v server/*.go -run TestHTTPServer_ContextPool
+func TestHTTPServer_ContextPool(t *testing.T) {
+
+	var retained, copied *Context
+	serv := New(":8081")
+	serv.Get("/user/:id", func(ctx *Context) {
+		ctx.Set("user", ctx.PathValue("id").val)
+		_ = ctx.QueryValue("tab")
+		retained = ctx
+		copied = ctx.Copy()
+		ctx.WriteString(http.StatusOK, []byte("ok"))
+	})
+	serv.Get("/order", func(ctx *Context) {
+		// 复用的 ctx 不能带着上一个请求的数据
+		_, exists := ctx.Get("user")
+		require.False(t, exists)
+		require.Empty(t, ctx.PathParams)
+		require.Empty(t, ctx.QueryParams)
+		require.Equal(t, 0, ctx.Index)
+		ctx.WriteString(http.StatusOK, []byte(ctx.MatchedPath))
+	})
+
+	resp := httptest.NewRecorder()
+	serv.ServeHTTP(resp, httptest.NewRequest(http.MethodGet, "/user/1?tab=fav", nil))
+	require.Equal(t, http.StatusOK, resp.Code)
+
+	// 请求结束之后继续使用 ctx 会 panic
+	require.PanicsWithValue(t, ErrContextReleased, func() {
+		retained.Get("user")
+	})
+	// Copy 之后的 ctx 可以继续使用
+	user, _ := copied.Get("user")
+	require.Equal(t, "1", user)
+	require.Equal(t, "1", copied.PathValue("id").val)
+	require.Equal(t, "/user/:id", copied.MatchedPath)
+
+	resp = httptest.NewRecorder()
+	serv.ServeHTTP(resp, httptest.NewRequest(http.MethodGet, "/order", nil))
+	require.Equal(t, http.StatusOK, resp.Code)
+	require.Equal(t, "/order", resp.Body.String())
+}
+
+// go test -v server/*.go -run TestHTTPServer_ContextPoolReuse
+func TestHTTPServer_ContextPoolReuse(t *testing.T) {
+
+	// 被下一个请求复用之后, 持有的 ctx 不再 panic, 读到的是新请求的数据, 只有 Copy 是安全的
+	var retained, copied *Context
+	serv := New(":8081")
+	serv.Get("/user/:id", func(ctx *Context) {
+		ctx.Set("user", ctx.PathValue("id").val)
+		retained = ctx
+		copied = ctx.Copy()
+	})
+	serv.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/user/1", nil))
+	require.PanicsWithValue(t, ErrContextReleased, func() {
+		retained.Get("user")
+	})
+
+	// sync.Pool 不保证取回同一个 ctx, 直接按 acquireContext 的方式复用
+	req := httptest.NewRequest(http.MethodGet, "/order", nil)
+	retained.reset(req, httptest.NewRecorder(), nil)
+	require.NotPanics(t, func() {
+		retained.Get("user")
+	})
+	_, exists := retained.Get("user")
+	require.False(t, exists)
+	require.Same(t, req, retained.Req)
+
+	user, _ := copied.Get("user")
+	require.Equal(t, "1", user)
+}
+
+// go test -v server/*.go -run TestHTTPServer_ContextPoolDisabled
+func TestHTTPServer_ContextPoolDisabled(t *testing.T) {
+
+	var retained *Context
+	serv := New(":8081", WithContextPool(false))
+	serv.Get("/user", func(ctx *Context) {
+		ctx.Set("user", "1")
+		retained = ctx
+	})
+	serv.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/user", nil))
+
+	user, _ := retained.Get("user")
+	require.Equal(t, "1", user)
+}
+
+// discardWriter 不记录响应, 避免 httptest.ResponseRecorder 的内存分配影响测试结果
+type discardWriter struct {
+	header http.Header
+}
+
+func (w *discardWriter) Header() http.Header {
+	return w.header
+}
+
+func (w *discardWriter) Write(data []byte) (int, error) {
+	return len(data), nil
+}
+
+func (w *discardWriter) WriteHeader(statusCode int) {}
+
+// go test -bench=BenchmarkHTTPServer_ServeHTTP -benchmem -run=^$ ./server/
+func BenchmarkHTTPServer_ServeHTTP(b *testing.B) {
+	handler := func(ctx *Context) {
+		ctx.WriteString(http.StatusOK, []byte("ok"))
+	}
+	benchCases := []struct {
+		name string
+		opts []Option
+		path string
+	}{
+		{name: "static/pool", path: "/health"},
+		{name: "static/no-pool", opts: []Option{WithContextPool(false)}, path: "/health"},
+		{name: "param/pool", path: "/user/1"},
+		{name: "param/no-pool", opts: []Option{WithContextPool(false)}, path: "/user/1"},
+	}
+	for _, bc := range benchCases {
+		b.Run(bc.name, func(b *testing.B) {
+			serv := New(":8081", bc.opts...)
+			serv.Get("/health", handler)
+			serv.Get("/user/:id", handler)
+			req := httptest.NewRequest(http.MethodGet, bc.path, nil)
+			w := &discardWriter{header: make(http.Header)}
+			b.ReportAllocs()
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				serv.ServeHTTP(w, req)
+			}
+		})
+	}
+}
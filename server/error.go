@@ -1,7 +1,56 @@
 package server
 
-import "errors"
+import (
+	"errors"
+	"fmt"
+)
 
 var (
 	ErrStartServerTimeout = errors.New("start server timeout")
+	ErrRouteNameNotFound  = errors.New("route name not found")
+	ErrMissingURLParam    = errors.New("missing url param")
+	ErrInvalidURLParams   = errors.New("invalid url params")
+
+	ErrRestartNotSupported   = errors.New("graceful restart not supported")
+	ErrContextReleased       = errors.New("context used after the request finished, use ctx.Copy() instead")
+	ErrInvalidBindTarget     = errors.New("bind target must be a non-nil pointer to struct")
+	ErrUnknownValidationRule = errors.New("unknown validation rule")
+	ErrUnsupportedValue      = errors.New("value not supported by codec")
+
+	ErrMethodNotSupported = errors.New("method not supported")
+	ErrDuplicateRoute     = errors.New("duplicate route")
+	ErrParamConflict      = errors.New("path param name conflict")
+	ErrWildcardConflict   = errors.New("wildcard conflicts with path param")
+	ErrInvalidCatchAll    = errors.New("catch-all param should be the last segment")
+	ErrInvalidParam       = errors.New("invalid path param")
+	ErrRouteNotFound      = errors.New("route not found")
 )
+
+// RouteError 注册路由时的冲突错误, 可以通过 errors.Is 判断具体的错误类型
+type RouteError struct {
+	Err    error
+	Method string
+	Path   string
+	// Existing 与之冲突的已注册路由
+	Existing string
+}
+
+func newRouteError(err error, method string, path string, existing string) *RouteError {
+	return &RouteError{
+		Err:      err,
+		Method:   method,
+		Path:     path,
+		Existing: existing,
+	}
+}
+
+func (e *RouteError) Error() string {
+	if e.Existing == "" {
+		return fmt.Sprintf("route [%s] %s: %v", e.Method, e.Path, e.Err)
+	}
+	return fmt.Sprintf("route [%s] %s: %v, conflicts with %s", e.Method, e.Path, e.Err, e.Existing)
+}
+
+func (e *RouteError) Unwrap() error {
+	return e.Err
+}
@@ -111,7 +111,8 @@ func NewStaticFileHandler(cacheSize int, cacheTTL time.Duration) *StaticFileHand
 
 func (s *StaticFileHandler) Handle(dir string) HandleFunc {
 	return func(ctx *Context) {
-		file := ctx.PathParams.Get("file")
+		// path.Clean 避免别人通过../../作为文件名,来访问 dir 之外的文件
+		file := strings.TrimPrefix(path.Clean("/"+ctx.PathParams.Get("file")), "/")
 
 		// 检查缓存是否有数据
 		data, ok := s.cache.Get(file)
@@ -0,0 +1,115 @@
+package server
+
+import (
+	"net/http"
+	"path"
+	"strings"
+)
+
+// RouterGroup 路由分组, 组内的路由共享前缀和中间件
+type RouterGroup struct {
+	// host 为空时注册到默认的路由树
+	host        string
+	prefix      string
+	middlewares []HandleFunc
+	// 组内路由的匹配条件
+	predicates []Predicate
+	serv       *HTTPServer
+}
+
+// Group 创建路由分组
+func (s *HTTPServer) Group(prefix string, middlewares ...HandleFunc) *RouterGroup {
+	return &RouterGroup{
+		prefix:      joinPath("", prefix),
+		middlewares: append([]HandleFunc{}, middlewares...),
+		serv:        s,
+	}
+}
+
+// Group 创建嵌套的路由分组, 继承父分组的前缀和中间件
+func (g *RouterGroup) Group(prefix string, middlewares ...HandleFunc) *RouterGroup {
+	return &RouterGroup{
+		host:        g.host,
+		prefix:      joinPath(g.prefix, prefix),
+		middlewares: g.combineMiddlewares(middlewares),
+		predicates:  append([]Predicate{}, g.predicates...),
+		serv:        g.serv,
+	}
+}
+
+// Use 添加分组中间件, 只对之后注册的路由生效
+func (g *RouterGroup) Use(middlewares ...HandleFunc) {
+	g.middlewares = append(g.middlewares, middlewares...)
+}
+
+// AddRoute 添加路由, 执行顺序为: 全局中间件 -> 分组中间件 -> 路由中间件 -> handler
+func (g *RouterGroup) AddRoute(method string, path string, handler HandleFunc, middlewares ...HandleFunc) *Route {
+	route, err := g.serv.addRoute(g.host, method, joinPath(g.prefix, path), g.serv.table().router.strict, g.predicates, handler, g.combineMiddlewares(middlewares)...)
+	if err != nil {
+		panic(err)
+	}
+	return route
+}
+
+// AddRouteE 添加路由, 和已注册的路由冲突时返回 *RouteError
+func (g *RouterGroup) AddRouteE(method string, path string, handler HandleFunc, middlewares ...HandleFunc) (*Route, error) {
+	return g.serv.addRoute(g.host, method, joinPath(g.prefix, path), true, g.predicates, handler, g.combineMiddlewares(middlewares)...)
+}
+
+func (g *RouterGroup) Get(path string, handler HandleFunc, middlewares ...HandleFunc) *Route {
+	return g.AddRoute(http.MethodGet, path, handler, middlewares...)
+}
+
+func (g *RouterGroup) Head(path string, handler HandleFunc, middlewares ...HandleFunc) *Route {
+	return g.AddRoute(http.MethodHead, path, handler, middlewares...)
+}
+
+func (g *RouterGroup) Post(path string, handler HandleFunc, middlewares ...HandleFunc) *Route {
+	return g.AddRoute(http.MethodPost, path, handler, middlewares...)
+}
+
+func (g *RouterGroup) Put(path string, handler HandleFunc, middlewares ...HandleFunc) *Route {
+	return g.AddRoute(http.MethodPut, path, handler, middlewares...)
+}
+
+func (g *RouterGroup) Patch(path string, handler HandleFunc, middlewares ...HandleFunc) *Route {
+	return g.AddRoute(http.MethodPatch, path, handler, middlewares...)
+}
+
+func (g *RouterGroup) Delete(path string, handler HandleFunc, middlewares ...HandleFunc) *Route {
+	return g.AddRoute(http.MethodDelete, path, handler, middlewares...)
+}
+
+func (g *RouterGroup) Connect(path string, handler HandleFunc, middlewares ...HandleFunc) *Route {
+	return g.AddRoute(http.MethodConnect, path, handler, middlewares...)
+}
+
+func (g *RouterGroup) Options(path string, handler HandleFunc, middlewares ...HandleFunc) *Route {
+	return g.AddRoute(http.MethodOptions, path, handler, middlewares...)
+}
+
+func (g *RouterGroup) Trace(path string, handler HandleFunc, middlewares ...HandleFunc) *Route {
+	return g.AddRoute(http.MethodTrace, path, handler, middlewares...)
+}
+
+// combineMiddlewares 分组中间件在前, 路由中间件在后
+func (g *RouterGroup) combineMiddlewares(middlewares []HandleFunc) []HandleFunc {
+	mws := make([]HandleFunc, 0, len(g.middlewares)+len(middlewares))
+	mws = append(mws, g.middlewares...)
+	return append(mws, middlewares...)
+}
+
+// joinPath 拼接分组前缀和路由, 保留路由末尾的 /
+func joinPath(prefix string, relativePath string) string {
+	if relativePath == "" {
+		if prefix == "" {
+			return "/"
+		}
+		return prefix
+	}
+	joined := path.Join("/", prefix, relativePath)
+	if strings.HasSuffix(relativePath, "/") && !strings.HasSuffix(joined, "/") {
+		return joined + "/"
+	}
+	return joined
+}
@@ -0,0 +1,93 @@
+package server
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+// go test -v server/*.go -run TestRouterGroup
+func TestRouterGroup(t *testing.T) {
+
+	var logs []string
+	mark := func(name string) HandleFunc {
+		return func(ctx *Context) {
+			logs = append(logs, name)
+			ctx.Next()
+		}
+	}
+
+	serv := New(":8081")
+	serv.Use(mark("global"))
+
+	api := serv.Group("/api", mark("api"))
+	v1 := api.Group("v1", mark("v1"))
+	v1.Use(mark("v1-use"))
+	v1.Get("/user/:id", func(ctx *Context) {
+		logs = append(logs, "handler:"+ctx.PathValue("id").val)
+	}, mark("route"))
+	api.Post("/login", func(ctx *Context) {
+		logs = append(logs, "login")
+	})
+
+	testCases := []struct {
+		name     string
+		method   string
+		path     string
+		wantCode int
+		wantLogs []string
+	}{
+		{
+			name:     "nested group",
+			method:   http.MethodGet,
+			path:     "/api/v1/user/1",
+			wantCode: http.StatusOK,
+			wantLogs: []string{"global", "api", "v1", "v1-use", "route", "handler:1"},
+		},
+		{
+			name:     "group middleware should not leak into parent",
+			method:   http.MethodPost,
+			path:     "/api/login",
+			wantCode: http.StatusOK,
+			wantLogs: []string{"global", "api", "login"},
+		},
+		{
+			name:     "route without prefix",
+			method:   http.MethodGet,
+			path:     "/v1/user/1",
+			wantCode: http.StatusNotFound,
+			wantLogs: []string{"global"},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			logs = nil
+			resp := httptest.NewRecorder()
+			req := httptest.NewRequest(tc.method, tc.path, nil)
+			serv.ServeHTTP(resp, req)
+			require.Equal(t, tc.wantCode, resp.Code)
+			require.Equal(t, tc.wantLogs, logs)
+		})
+	}
+}
+
+func TestJoinPath(t *testing.T) {
+	testCases := []struct {
+		prefix string
+		path   string
+		want   string
+	}{
+		{prefix: "", path: "", want: "/"},
+		{prefix: "", path: "user", want: "/user"},
+		{prefix: "/api", path: "", want: "/api"},
+		{prefix: "/api", path: "/", want: "/api/"},
+		{prefix: "/api/", path: "/v1/", want: "/api/v1/"},
+		{prefix: "/api", path: "/user/:id", want: "/api/user/:id"},
+	}
+	for _, tc := range testCases {
+		require.Equal(t, tc.want, joinPath(tc.prefix, tc.path))
+	}
+}
@@ -1,14 +1,82 @@
 package server
 
-import "net/http"
+import (
+	"net/http"
+	"net/url"
+	"sort"
+)
 
-type HTTPHandler struct {
-	mux *http.ServeMux
+// ServeHTTP 让 HandleFunc 可以作为 http.Handler 使用, 例如注册到 http.ServeMux
+// 没有经过 HTTPServer 的路由, 所以 ctx 中没有路径参数和模板引擎
+func (h HandleFunc) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	ctx := NewContext(r, w, nil)
+	ctx.HandlerChain = []HandleFunc{h}
+	h(ctx)
+	ctx.writer.flushBuffer()
 }
 
-// ServeHTTP implements http.Handler.
-func (h *HTTPHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
-	h.mux.ServeHTTP(w, r)
+var _ http.Handler = HandleFunc(nil)
+
+// WrapHandler 把 http.Handler 转换成 HandleFunc, 例如 promhttp.Handler()
+func WrapHandler(h http.Handler) HandleFunc {
+	return func(ctx *Context) {
+		h.ServeHTTP(ctx.Resp, ctx.Req)
+	}
+}
+
+// WrapMiddleware 把 func(http.Handler) http.Handler 形式的中间件转换成 HandleFunc
+// 中间件替换的 ResponseWriter 和 Request 对之后的 handler 生效, 中间件没有调用 next 时中断调用链
+func WrapMiddleware(mw func(next http.Handler) http.Handler) HandleFunc {
+	return func(ctx *Context) {
+		called := false
+		resp, req := ctx.Resp, ctx.Req
+		next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			called = true
+			ctx.Resp, ctx.Req = w, r
+			ctx.Next()
+			ctx.Resp, ctx.Req = resp, req
+		})
+		mw(next).ServeHTTP(resp, req)
+		if !called {
+			ctx.Abort()
+		}
+	}
 }
 
-var _ http.Handler = (*HTTPHandler)(nil)
+// mountParam Mount 注册的路由中, 捕获剩余路径的参数名
+const mountParam = "mountpath"
+
+// Mount 把 http.Handler 挂载到 prefix 下, 所有 method 的请求都会交给 h 处理
+// 转发前会去掉请求路径中的 prefix, 例如挂载到 /admin 时, /admin/users 转发为 /users
+func (s *HTTPServer) Mount(prefix string, h http.Handler, middlewares ...HandleFunc) {
+	s.Group("").Mount(prefix, h, middlewares...)
+}
+
+// Mount 把 http.Handler 挂载到分组的 prefix 下, 继承分组的中间件和匹配条件
+func (g *RouterGroup) Mount(prefix string, h http.Handler, middlewares ...HandleFunc) {
+	mounted := g.Group(prefix, middlewares...)
+	handler := mountHandler(h)
+	supported := g.serv.table().router.supportedMethod
+	methods := make([]string, 0, len(supported))
+	for method := range supported {
+		methods = append(methods, method)
+	}
+	sort.Strings(methods)
+	for _, method := range methods {
+		mounted.AddRoute(method, "", handler)
+		mounted.AddRoute(method, "*"+mountParam, handler)
+	}
+}
+
+// mountHandler 用 prefix 之后的路径转发请求
+func mountHandler(h http.Handler) HandleFunc {
+	return func(ctx *Context) {
+		req := new(http.Request)
+		*req = *ctx.Req
+		req.URL = new(url.URL)
+		*req.URL = *ctx.Req.URL
+		req.URL.Path = "/" + ctx.PathParams.Get(mountParam)
+		req.URL.RawPath = ""
+		h.ServeHTTP(ctx.Resp, req)
+	}
+}
@@ -0,0 +1,158 @@
+package server
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+// go test -v server/*.go -run TestHTTPServer_Mount
+func TestHTTPServer_Mount(t *testing.T) {
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(r.Method + " " + r.URL.Path + "?" + r.URL.RawQuery))
+	})
+
+	var logs []string
+	serv := New(":8081")
+	serv.Mount("/admin", mux, func(ctx *Context) {
+		logs = append(logs, "admin")
+		ctx.Next()
+	})
+	serv.Group("/api").Mount("/v1/", mux)
+	serv.Get("/admin-ui", func(ctx *Context) {
+		ctx.WriteString(http.StatusOK, []byte("admin-ui"))
+	})
+
+	testCases := []struct {
+		name     string
+		method   string
+		path     string
+		wantCode int
+		wantBody string
+		wantLogs []string
+	}{
+		{
+			name:     "prefix",
+			method:   http.MethodGet,
+			path:     "/admin",
+			wantCode: http.StatusOK,
+			wantBody: "GET /?",
+			wantLogs: []string{"admin"},
+		},
+		{
+			name:     "sub path",
+			method:   http.MethodDelete,
+			path:     "/admin/users/1/?force=true",
+			wantCode: http.StatusOK,
+			wantBody: "DELETE /users/1/?force=true",
+			wantLogs: []string{"admin"},
+		},
+		{
+			name:     "group",
+			method:   http.MethodPost,
+			path:     "/api/v1/order",
+			wantCode: http.StatusOK,
+			wantBody: "POST /order?",
+		},
+		{
+			name:     "sibling route",
+			method:   http.MethodGet,
+			path:     "/admin-ui",
+			wantCode: http.StatusOK,
+			wantBody: "admin-ui",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			logs = nil
+			resp := httptest.NewRecorder()
+			req := httptest.NewRequest(tc.method, tc.path, nil)
+			serv.ServeHTTP(resp, req)
+			require.Equal(t, tc.wantCode, resp.Code)
+			require.Equal(t, tc.wantBody, resp.Body.String())
+			require.Equal(t, tc.wantLogs, logs)
+		})
+	}
+}
+
+// go test -v server/*.go -run TestWrapMiddleware
+func TestWrapMiddleware(t *testing.T) {
+
+	setHeader := func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("X-Wrapped", "true")
+			next.ServeHTTP(w, r)
+		})
+	}
+	auth := func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.Header.Get("Authorization") == "" {
+				w.WriteHeader(http.StatusUnauthorized)
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+
+	var called bool
+	serv := New(":8081")
+	serv.Use(WrapMiddleware(setHeader), WrapMiddleware(auth))
+	serv.Get("/user", func(ctx *Context) {
+		called = true
+		ctx.WriteString(http.StatusOK, []byte("ok"))
+	})
+	serv.Get("/health", WrapHandler(HandleFunc(func(ctx *Context) {
+		ctx.WriteString(http.StatusOK, []byte(ctx.Req.URL.Path))
+	})))
+
+	testCases := []struct {
+		name       string
+		path       string
+		auth       string
+		wantCode   int
+		wantBody   string
+		wantCalled bool
+	}{
+		{
+			name:     "next not called",
+			path:     "/user",
+			wantCode: http.StatusUnauthorized,
+		},
+		{
+			name:       "next called",
+			path:       "/user",
+			auth:       "token",
+			wantCode:   http.StatusOK,
+			wantBody:   "ok",
+			wantCalled: true,
+		},
+		{
+			name:     "handler func as http.Handler",
+			path:     "/health",
+			auth:     "token",
+			wantCode: http.StatusOK,
+			wantBody: "/health",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			called = false
+			resp := httptest.NewRecorder()
+			req := httptest.NewRequest(http.MethodGet, tc.path, nil)
+			if tc.auth != "" {
+				req.Header.Set("Authorization", tc.auth)
+			}
+			serv.ServeHTTP(resp, req)
+			require.Equal(t, tc.wantCode, resp.Code)
+			require.Equal(t, tc.wantBody, resp.Body.String())
+			require.Equal(t, tc.wantCalled, called)
+			require.Equal(t, "true", resp.Header().Get("X-Wrapped"))
+		})
+	}
+}
@@ -0,0 +1,144 @@
+package server
+
+import (
+	"errors"
+	"net"
+	"strings"
+)
+
+// hostMethod host 路由树使用的 method
+const hostMethod = "HOST"
+
+// hostRouter 按 host 分发到不同的路由树
+// host 的 label 倒序之后作为 path 注册到 router 中, 例如 :tenant.example.com => /com/example/:tenant
+// 这样就可以复用 router 的静态, 路径参数和 * 匹配
+type hostRouter struct {
+	tree    *router
+	routers map[string]*hostEntry
+	// 注册顺序, 用来稳定地输出路由表
+	patterns []string
+}
+
+type hostEntry struct {
+	pattern string
+	router  *router
+}
+
+func newHostRouter() *hostRouter {
+	tree := newRouter()
+	tree.supportedMethod = map[string]bool{hostMethod: true}
+	// 域名不区分大小写
+	tree.ignoreCase = true
+	return &hostRouter{
+		tree:    tree,
+		routers: make(map[string]*hostEntry),
+	}
+}
+
+// Router 返回 host 对应的路由树, 不存在时返回 nil
+func (h *hostRouter) Router(pattern string) *router {
+	if entry, ok := h.routers[hostKey(pattern)]; ok {
+		return entry.router
+	}
+	return nil
+}
+
+// Add 添加 host 对应的路由树, 校验模式注册, 和已注册的 host 冲突时返回 *RouteError
+// 例如 :a.example.com 和 :b.example.com 共用同一个参数节点, 后注册的会让之前的 host 无法匹配
+func (h *hostRouter) Add(pattern string, r *router) error {
+	key := hostKey(pattern)
+	if _, err := h.tree.addRoute(hostMethod, key, true, nil, nil, func(ctx *Context) {}); err != nil {
+		var routeErr *RouteError
+		if !errors.As(err, &routeErr) {
+			return err
+		}
+		existing := routeErr.Existing
+		if entry, ok := h.routers[existing]; ok {
+			existing = entry.pattern
+		}
+		return newRouteError(routeErr.Err, hostMethod, pattern, existing)
+	}
+	h.routers[key] = &hostEntry{
+		pattern: pattern,
+		router:  r,
+	}
+	h.patterns = append(h.patterns, pattern)
+	return nil
+}
+
+// hostKey host 在路由树中的 path, 只把静态的 label 转成小写, 参数名和正则保持原样
+// 匹配时路由树本身忽略大小写
+func hostKey(pattern string) string {
+	labels := strings.Split(pattern, ".")
+	for i, label := range labels {
+		if !strings.HasPrefix(label, ":") && !strings.HasPrefix(label, "*") {
+			labels[i] = strings.ToLower(label)
+		}
+	}
+	return hostToPath(strings.Join(labels, "."))
+}
+
+func (h *hostRouter) clone() *hostRouter {
+	routers := make(map[string]*hostEntry, len(h.routers))
+	for key, entry := range h.routers {
+		routers[key] = &hostEntry{
+			pattern: entry.pattern,
+			router:  entry.router.clone(),
+		}
+	}
+	return &hostRouter{
+		tree:     h.tree.clone(),
+		routers:  routers,
+		patterns: append([]string{}, h.patterns...),
+	}
+}
+
+// Match 查找 host 对应的路由树, 以及 host 中的参数
+func (h *hostRouter) Match(host string) (*router, []pathParam, bool) {
+	if len(h.routers) == 0 {
+		return nil, nil, false
+	}
+	matchInfo, found := h.tree.FindRoute(hostMethod, hostToPath(stripPort(host)))
+	if !found {
+		return nil, nil, false
+	}
+	var params []pathParam
+	for key, vals := range matchInfo.pathParams {
+		for _, val := range vals {
+			params = append(params, pathParam{key: key, value: val})
+		}
+	}
+	return h.routers[matchInfo.node.matchedPath].router, params, true
+}
+
+// hostToPath 把 host 的 label 倒序转换成 path
+func hostToPath(host string) string {
+	labels := strings.Split(strings.Trim(host, "."), ".")
+	var sb strings.Builder
+	sb.Grow(len(host) + 1)
+	for i := len(labels) - 1; i >= 0; i-- {
+		sb.WriteByte('/')
+		sb.WriteString(labels[i])
+	}
+	return sb.String()
+}
+
+// stripPort 去掉 host 中的端口
+func stripPort(host string) string {
+	if h, _, err := net.SplitHostPort(host); err == nil {
+		return h
+	}
+	return host
+}
+
+// Host 创建只匹配指定 host 的路由分组, 支持精确匹配和 :tenant.example.com 这样的参数匹配
+// host 中的参数和路径参数一样, 可以通过 ctx.PathValue 获取
+// 没有匹配任何 host 的请求, 使用 HTTPServer 上直接注册的路由
+func (s *HTTPServer) Host(pattern string, middlewares ...HandleFunc) *RouterGroup {
+	return &RouterGroup{
+		host:        pattern,
+		prefix:      "/",
+		middlewares: append([]HandleFunc{}, middlewares...),
+		serv:        s,
+	}
+}
@@ -0,0 +1,140 @@
+package server
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+// go test -v server/*.go -run TestHTTPServer_Host
+func TestHTTPServer_Host(t *testing.T) {
+
+	write := func(name string) HandleFunc {
+		return func(ctx *Context) {
+			ctx.WriteString(http.StatusOK, []byte(name))
+		}
+	}
+
+	serv := New(":8081")
+	serv.Get("/user/:id", write("default"))
+	serv.Host("api.example.com").Get("/user/:id", write("api"))
+	serv.Host("admin.example.com").Group("/admin").Get("/user/:id", write("admin")).Name("admin-user")
+	serv.Host(":tenantID.Example.com").Get("/user/:id", func(ctx *Context) {
+		ctx.WriteString(http.StatusOK, []byte(ctx.PathValue("tenantID").val+":"+ctx.PathValue("id").val))
+	})
+
+	testCases := []struct {
+		name     string
+		host     string
+		path     string
+		wantCode int
+		wantBody string
+	}{
+		{
+			name:     "exact host",
+			host:     "api.example.com",
+			path:     "/user/1",
+			wantCode: http.StatusOK,
+			wantBody: "api",
+		},
+		{
+			name:     "host with port",
+			host:     "api.example.com:8081",
+			path:     "/user/1",
+			wantCode: http.StatusOK,
+			wantBody: "api",
+		},
+		{
+			name:     "host is case insensitive",
+			host:     "API.Example.com",
+			path:     "/user/1",
+			wantCode: http.StatusOK,
+			wantBody: "api",
+		},
+		{
+			name:     "host with group",
+			host:     "admin.example.com",
+			path:     "/admin/user/1",
+			wantCode: http.StatusOK,
+			wantBody: "admin",
+		},
+		{
+			name:     "routes are isolated between hosts",
+			host:     "admin.example.com",
+			path:     "/user/1",
+			wantCode: http.StatusNotFound,
+		},
+		{
+			name:     "host param",
+			host:     "acme.example.com",
+			path:     "/user/1",
+			wantCode: http.StatusOK,
+			wantBody: "acme:1",
+		},
+		{
+			name:     "fallback to default routes",
+			host:     "localhost:8081",
+			path:     "/user/1",
+			wantCode: http.StatusOK,
+			wantBody: "default",
+		},
+		{
+			name:     "host param matches only one label",
+			host:     "a.b.example.com",
+			path:     "/user/1",
+			wantCode: http.StatusOK,
+			wantBody: "default",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			resp := httptest.NewRecorder()
+			req := httptest.NewRequest(http.MethodGet, tc.path, nil)
+			req.Host = tc.host
+			serv.ServeHTTP(resp, req)
+			require.Equal(t, tc.wantCode, resp.Code)
+			if tc.wantBody != "" {
+				require.Equal(t, tc.wantBody, resp.Body.String())
+			}
+		})
+	}
+
+	u, err := serv.URLFor("admin-user", "id", 1)
+	require.NoError(t, err)
+	require.Equal(t, "/admin/user/1", u)
+
+	routes := serv.Routes()
+	require.Len(t, routes, 4)
+	require.Equal(t, "", routes[0].Host)
+	require.Equal(t, "api.example.com", routes[1].Host)
+	require.Equal(t, "admin.example.com", routes[2].Host)
+	require.Equal(t, ":tenantID.Example.com", routes[3].Host)
+}
+
+// go test -v server/*.go -run TestHTTPServer_HostParamConflict
+func TestHTTPServer_HostParamConflict(t *testing.T) {
+	handler := func(ctx *Context) {
+		ctx.WriteString(http.StatusOK, []byte(ctx.MatchedPath))
+	}
+
+	serv := New(":8081")
+	serv.Host(":a.example.com").Get("/x", handler)
+	// 参数名不同的 host 共用同一个参数节点, 不能覆盖之前注册的 host
+	_, err := serv.Host(":b.example.com").AddRouteE(http.MethodGet, "/y", handler)
+	require.ErrorIs(t, err, ErrParamConflict)
+	require.EqualError(t, err, "route [HOST] :b.example.com: path param name conflict, conflicts with :a.example.com")
+	require.Panics(t, func() {
+		serv.Host(":b.example.com").Get("/y", handler)
+	})
+
+	resp := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodGet, "/x", nil)
+	req.Host = "t.example.com"
+	serv.ServeHTTP(resp, req)
+	require.Equal(t, http.StatusOK, resp.Code)
+	require.Equal(t, "/x", resp.Body.String())
+	require.Len(t, serv.Routes(), 1)
+}
@@ -1,5 +1,11 @@
 package server
 
+import (
+	"net"
+	"os"
+	"time"
+)
+
 type Option func(s *HTTPServer)
 
 func WithTplEngine(eg TemplateEngine) Option {
@@ -13,3 +19,141 @@ func WithStaticFileHandler(handler *StaticFileHandler) Option {
 		s.staticHandler = handler
 	}
 }
+
+// WithMethodNotAllowed 路径在其他 method 下存在时, 是否返回 405 和 Allow 头, 默认开启
+func WithMethodNotAllowed(enable bool) Option {
+	return func(s *HTTPServer) {
+		s.handleMethodNotAllowed = enable
+	}
+}
+
+// WithAutoOptions 是否自动响应已注册路径的 OPTIONS 请求, 默认开启
+func WithAutoOptions(enable bool) Option {
+	return func(s *HTTPServer) {
+		s.handleOptions = enable
+	}
+}
+
+// WithNotFoundHandler 自定义 404 handler, 会经过全局中间件
+func WithNotFoundHandler(handler HandleFunc) Option {
+	return func(s *HTTPServer) {
+		s.notFoundHandler = handler
+	}
+}
+
+// WithMethodNotAllowedHandler 自定义 405 handler, 会经过全局中间件, 执行前已经设置好 Allow 头
+func WithMethodNotAllowedHandler(handler HandleFunc) Option {
+	return func(s *HTTPServer) {
+		s.methodNotAllowedHandler = handler
+	}
+}
+
+// WithRedirectTrailingSlash 请求路径和注册的路由只差末尾的 / 时, 重定向到注册的形式, GET 使用 301, 其他 method 使用 308
+func WithRedirectTrailingSlash(enable bool) Option {
+	return func(s *HTTPServer) {
+		s.redirectTrailingSlash = enable
+	}
+}
+
+// WithRedirectFixedPath 请求路径中包含 .. 或者 // 时, 清理后重定向
+func WithRedirectFixedPath(enable bool) Option {
+	return func(s *HTTPServer) {
+		s.redirectFixedPath = enable
+	}
+}
+
+// WithCaseInsensitive 静态路由匹配时忽略大小写
+func WithCaseInsensitive(enable bool) Option {
+	return func(s *HTTPServer) {
+		s.table().router.ignoreCase = enable
+	}
+}
+
+// WithRouteValidation 开启校验模式, 重复注册路由和路径参数名字不一致时 AddRoute 会 panic
+func WithRouteValidation(enable bool) Option {
+	return func(s *HTTPServer) {
+		s.table().router.strict = enable
+	}
+}
+
+// WithShutdownTimeout Run 和 ShutDown 优雅退出时等待请求处理完成的最长时间, 默认 15s
+// 同时也是每个退出钩子的默认超时时间
+func WithShutdownTimeout(timeout time.Duration) Option {
+	return func(s *HTTPServer) {
+		s.shutDownTimeout = timeout
+	}
+}
+
+// WithReadTimeout 读取整个请求, 包括请求体的超时时间, 默认不限制
+func WithReadTimeout(timeout time.Duration) Option {
+	return func(s *HTTPServer) {
+		s.readTimeout = timeout
+	}
+}
+
+// WithReadHeaderTimeout 读取请求头的超时时间, 用来防御 slowloris 这类慢速攻击, 默认 10s
+func WithReadHeaderTimeout(timeout time.Duration) Option {
+	return func(s *HTTPServer) {
+		s.readHeaderTimeout = timeout
+	}
+}
+
+// WithWriteTimeout 写响应的超时时间, 默认不限制, 流式响应需要谨慎设置
+func WithWriteTimeout(timeout time.Duration) Option {
+	return func(s *HTTPServer) {
+		s.writeTimeout = timeout
+	}
+}
+
+// WithIdleTimeout keep-alive 连接的空闲时间, 默认使用 ReadTimeout
+func WithIdleTimeout(timeout time.Duration) Option {
+	return func(s *HTTPServer) {
+		s.idleTimeout = timeout
+	}
+}
+
+// WithMaxHeaderBytes 请求头的最大字节数, 默认使用 http.DefaultMaxHeaderBytes
+func WithMaxHeaderBytes(n int) Option {
+	return func(s *HTTPServer) {
+		s.maxHeaderBytes = n
+	}
+}
+
+// WithListener 使用已有的 listener, 例如 unix socket, systemd 传入的 socket 或者开启了 SO_REUSEPORT 的 socket
+// 设置之后忽略 New 传入的地址
+// 平滑重启的子进程中 Start 会改用父进程传过来的 listener, 需要包装 listener 时用 Listen 创建
+func WithListener(l net.Listener) Option {
+	return func(s *HTTPServer) {
+		s.listener = l
+	}
+}
+
+// WithRestartSignals Run 收到这些信号时平滑重启, 例如 syscall.SIGHUP, syscall.SIGUSR2, 只支持 unix 系统
+func WithRestartSignals(signals ...os.Signal) Option {
+	return func(s *HTTPServer) {
+		s.restartSignals = signals
+	}
+}
+
+// WithContextPool 是否通过 sync.Pool 复用 Context, 默认开启
+// 请求结束之后还持有的 ctx 被复用时会读写新请求的数据, 怀疑有 handler 这样使用 ctx 时, 可以关闭复用来排查
+func WithContextPool(enable bool) Option {
+	return func(s *HTTPServer) {
+		s.reuseContext = enable
+	}
+}
+
+// WithBufferedResponse 是否缓冲所有的响应, 默认关闭, 只需要缓冲部分路由时在中间件中调用 ctx.BufferResponse
+// 开启之后响应在调用链执行完才发送, 中间件可以改写状态码, 响应头和响应体, 不适合流式响应
+func WithBufferedResponse(enable bool) Option {
+	return func(s *HTTPServer) {
+		s.bufferResponse = enable
+	}
+}
+
+// WithValidationFormatter 自定义 ctx.MustBind 校验失败时的响应, 默认返回 422 和 {"errors": ValidationErrors}
+func WithValidationFormatter(formatter ValidationFormatter) Option {
+	return func(s *HTTPServer) {
+		s.validator.formatter = formatter
+	}
+}
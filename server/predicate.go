@@ -0,0 +1,132 @@
+package server
+
+import (
+	"mime"
+	"net/http"
+	"sort"
+	"strings"
+)
+
+// Predicate 路由匹配条件, 同一个 method + path 可以按条件分发到不同的 handler
+type Predicate interface {
+	Match(req *http.Request) bool
+	// String 描述匹配条件, 用于打印路由表和检查重复注册
+	String() string
+}
+
+type predicate struct {
+	desc  string
+	match func(req *http.Request) bool
+}
+
+func (p predicate) Match(req *http.Request) bool {
+	return p.match(req)
+}
+
+func (p predicate) String() string {
+	return p.desc
+}
+
+// NewPredicate 创建自定义的匹配条件, desc 相同的条件被认为是同一个条件
+func NewPredicate(desc string, match func(req *http.Request) bool) Predicate {
+	return predicate{desc: desc, match: match}
+}
+
+// Header 请求头 key 的值等于 value
+func Header(key string, value string) Predicate {
+	key = http.CanonicalHeaderKey(key)
+	return NewPredicate("header:"+key+"="+value, func(req *http.Request) bool {
+		for _, val := range req.Header.Values(key) {
+			if val == value {
+				return true
+			}
+		}
+		return false
+	})
+}
+
+// Query 查询参数 key 的值等于 value
+func Query(key string, value string) Predicate {
+	return NewPredicate("query:"+key+"="+value, func(req *http.Request) bool {
+		for _, val := range req.URL.Query()[key] {
+			if val == value {
+				return true
+			}
+		}
+		return false
+	})
+}
+
+// Accept 客户端接受 mediaType 类型的响应, 没有 Accept 请求头时认为接受任意类型
+// 和 Negotiate 的规则相同, 使用最具体的范围的权重, 权重为 0 时表示不接受
+func Accept(mediaType string) Predicate {
+	mediaType = strings.ToLower(mediaType)
+	return NewPredicate("accept:"+mediaType, func(req *http.Request) bool {
+		accept := req.Header.Get("Accept")
+		if accept == "" {
+			return true
+		}
+		return quality(parseAccept(accept), mediaType) > 0
+	})
+}
+
+// ContentType 请求体的类型为 mediaType, 忽略 charset 等参数
+func ContentType(mediaType string) Predicate {
+	mediaType = strings.ToLower(mediaType)
+	return NewPredicate("content-type:"+mediaType, func(req *http.Request) bool {
+		ct, _, err := mime.ParseMediaType(req.Header.Get("Content-Type"))
+		return err == nil && ct == mediaType
+	})
+}
+
+// matchMediaRange 判断 */*, text/* 这样的 mediaRange 是否包含 mediaType
+func matchMediaRange(mediaRange string, mediaType string) bool {
+	if mediaRange == "*/*" || mediaRange == mediaType {
+		return true
+	}
+	typ, ok := strings.CutSuffix(mediaRange, "/*")
+	return ok && strings.HasPrefix(mediaType, typ+"/")
+}
+
+// variant 带匹配条件的调用链
+type variant struct {
+	predicates    []Predicate
+	key           string
+	handlerChains []HandleFunc
+}
+
+func (v *variant) match(req *http.Request) bool {
+	for _, p := range v.predicates {
+		if !p.Match(req) {
+			return false
+		}
+	}
+	return true
+}
+
+// predicateKey 匹配条件排序之后的描述, 用来判断两个路由的匹配条件是否相同
+func predicateKey(predicates []Predicate) string {
+	descs := make([]string, 0, len(predicates))
+	for _, p := range predicates {
+		descs = append(descs, p.String())
+	}
+	sort.Strings(descs)
+	return strings.Join(descs, ",")
+}
+
+// When 创建带匹配条件的路由分组, 组内的路由只有在所有条件都满足时才会命中
+// 同一个路由按注册顺序检查匹配条件, 都不满足时使用没有匹配条件的路由
+func (s *HTTPServer) When(predicates ...Predicate) *RouterGroup {
+	return &RouterGroup{
+		prefix:     "/",
+		predicates: append([]Predicate{}, predicates...),
+		serv:       s,
+	}
+}
+
+// When 创建带匹配条件的子分组, 继承当前分组的前缀, 中间件和匹配条件
+func (g *RouterGroup) When(predicates ...Predicate) *RouterGroup {
+	child := g.Group("")
+	child.predicates = append(child.predicates, predicates...)
+	return child
+}
@@ -0,0 +1,241 @@
+package server
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+// go test -v server/*.go -run TestHTTPServer_When
+func TestHTTPServer_When(t *testing.T) {
+
+	reply := func(body string) HandleFunc {
+		return func(ctx *Context) {
+			ctx.WriteString(http.StatusOK, []byte(body))
+		}
+	}
+
+	serv := New(":8081")
+	serv.Get("/user", reply("v1"))
+	serv.When(Header("X-API-Version", "2")).Get("/user", reply("v2"))
+	serv.When(Query("format", "csv")).Get("/user", reply("csv"))
+	serv.When(Accept("application/xml")).Get("/order", reply("xml"))
+	serv.When(Accept("application/json")).Get("/order", reply("json"))
+	api := serv.Group("/api").When(ContentType("application/json"))
+	api.Post("/user", reply("json body"))
+
+	testCases := []struct {
+		name     string
+		method   string
+		path     string
+		header   http.Header
+		wantCode int
+		wantBody string
+	}{
+		{
+			name:     "default",
+			method:   http.MethodGet,
+			path:     "/user",
+			wantCode: http.StatusOK,
+			wantBody: "v1",
+		},
+		{
+			name:     "header",
+			method:   http.MethodGet,
+			path:     "/user",
+			header:   http.Header{"X-Api-Version": []string{"2"}},
+			wantCode: http.StatusOK,
+			wantBody: "v2",
+		},
+		{
+			name:     "header not match",
+			method:   http.MethodGet,
+			path:     "/user",
+			header:   http.Header{"X-Api-Version": []string{"3"}},
+			wantCode: http.StatusOK,
+			wantBody: "v1",
+		},
+		{
+			name:     "query",
+			method:   http.MethodGet,
+			path:     "/user?format=csv",
+			wantCode: http.StatusOK,
+			wantBody: "csv",
+		},
+		{
+			name:     "registration order",
+			method:   http.MethodGet,
+			path:     "/user?format=csv",
+			header:   http.Header{"X-Api-Version": []string{"2"}},
+			wantCode: http.StatusOK,
+			wantBody: "v2",
+		},
+		{
+			name:     "accept",
+			method:   http.MethodGet,
+			path:     "/order",
+			header:   http.Header{"Accept": []string{"text/html, application/json;q=0.9"}},
+			wantCode: http.StatusOK,
+			wantBody: "json",
+		},
+		{
+			name:     "accept wildcard",
+			method:   http.MethodGet,
+			path:     "/order",
+			header:   http.Header{"Accept": []string{"application/*"}},
+			wantCode: http.StatusOK,
+			wantBody: "xml",
+		},
+		{
+			name:     "accept not match",
+			method:   http.MethodGet,
+			path:     "/order",
+			header:   http.Header{"Accept": []string{"text/html, application/json;q=0"}},
+			wantCode: http.StatusNotFound,
+		},
+		{
+			name:     "accept q=0.0",
+			method:   http.MethodGet,
+			path:     "/order",
+			header:   http.Header{"Accept": []string{"text/html, application/json;q=0.0"}},
+			wantCode: http.StatusNotFound,
+		},
+		{
+			// 最具体的范围排除了 json, */* 不会再匹配
+			name:     "accept excluded by specific range",
+			method:   http.MethodGet,
+			path:     "/order",
+			header:   http.Header{"Accept": []string{"application/json;q=0, application/xml;q=0.000, */*"}},
+			wantCode: http.StatusNotFound,
+		},
+		{
+			name:     "content type",
+			method:   http.MethodPost,
+			path:     "/api/user",
+			header:   http.Header{"Content-Type": []string{"application/json; charset=utf-8"}},
+			wantCode: http.StatusOK,
+			wantBody: "json body",
+		},
+		{
+			name:     "content type not match",
+			method:   http.MethodPost,
+			path:     "/api/user",
+			header:   http.Header{"Content-Type": []string{"text/plain"}},
+			wantCode: http.StatusNotFound,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			resp := httptest.NewRecorder()
+			req := httptest.NewRequest(tc.method, tc.path, nil)
+			for key, vals := range tc.header {
+				req.Header[key] = vals
+			}
+			serv.ServeHTTP(resp, req)
+			require.Equal(t, tc.wantCode, resp.Code)
+			if tc.wantBody != "" {
+				require.Equal(t, tc.wantBody, resp.Body.String())
+			}
+		})
+	}
+}
+
+// go test -v server/*.go -run TestHTTPServer_WhenFallback
+func TestHTTPServer_WhenFallback(t *testing.T) {
+
+	reply := func(ctx *Context) {
+		ctx.WriteString(http.StatusOK, []byte(ctx.MatchedPath))
+	}
+
+	serv := New(":8081", WithMethodNotAllowed(true))
+	serv.When(Header("X-V", "2")).Get("/order/export", reply)
+	serv.Get("/order/:id", reply)
+	serv.When(Header("X-V", "2")).Post("/report", reply)
+	serv.Get("/report", reply)
+
+	testCases := []struct {
+		name      string
+		method    string
+		path      string
+		header    http.Header
+		wantCode  int
+		wantBody  string
+		wantAllow string
+	}{
+		{
+			name:     "predicate matched",
+			method:   http.MethodGet,
+			path:     "/order/export",
+			header:   http.Header{"X-V": []string{"2"}},
+			wantCode: http.StatusOK,
+			wantBody: "/order/export",
+		},
+		{
+			// 只有带匹配条件的路由并且条件不满足时, 继续匹配路径参数
+			name:     "fallback to sibling route",
+			method:   http.MethodGet,
+			path:     "/order/export",
+			wantCode: http.StatusOK,
+			wantBody: "/order/:id",
+		},
+		{
+			name:      "allowed methods skip unmatched predicates",
+			method:    http.MethodPut,
+			path:      "/report",
+			wantCode:  http.StatusMethodNotAllowed,
+			wantAllow: "GET, OPTIONS",
+		},
+		{
+			name:      "allowed methods with matched predicates",
+			method:    http.MethodPut,
+			path:      "/report",
+			header:    http.Header{"X-V": []string{"2"}},
+			wantCode:  http.StatusMethodNotAllowed,
+			wantAllow: "GET, OPTIONS, POST",
+		},
+		{
+			name:      "allowed methods after fallback",
+			method:    http.MethodPost,
+			path:      "/order/export",
+			wantCode:  http.StatusMethodNotAllowed,
+			wantAllow: "GET, OPTIONS",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			resp := httptest.NewRecorder()
+			req := httptest.NewRequest(tc.method, tc.path, nil)
+			for key, vals := range tc.header {
+				req.Header[key] = vals
+			}
+			serv.ServeHTTP(resp, req)
+			require.Equal(t, tc.wantCode, resp.Code)
+			require.Equal(t, tc.wantAllow, resp.Header().Get("Allow"))
+			if tc.wantBody != "" {
+				require.Equal(t, tc.wantBody, resp.Body.String())
+			}
+		})
+	}
+}
+
+// go test -v server/*.go -run TestHTTPServer_WhenDuplicate
+func TestHTTPServer_WhenDuplicate(t *testing.T) {
+	handler := func(ctx *Context) {}
+
+	serv := New(":8081")
+	_, err := serv.When(Header("X-API-Version", "2")).AddRouteE(http.MethodGet, "/user", handler)
+	require.NoError(t, err)
+	_, err = serv.AddRouteE(http.MethodGet, "/user", handler)
+	require.NoError(t, err)
+	_, err = serv.When(Header("x-api-version", "2")).AddRouteE(http.MethodGet, "/user", handler)
+	require.ErrorIs(t, err, ErrDuplicateRoute)
+
+	routes := serv.Routes()
+	require.Len(t, routes, 2)
+	require.Nil(t, routes[0].Predicates)
+	require.Equal(t, []string{"header:X-Api-Version=2"}, routes[1].Predicates)
+}
@@ -0,0 +1,166 @@
+package server
+
+import (
+	"bufio"
+	"bytes"
+	"net"
+	"net/http"
+	"strconv"
+)
+
+// maxPooledBufferSize 超过这个大小的缓冲区不随 Context 复用, 避免一个大响应长期占用内存
+const maxPooledBufferSize = 64 * 1024
+
+// ResponseWriter 记录状态码, 写入的字节数, 以及响应头是否已经发送
+// Context.Resp 默认就是 ResponseWriter, 中间件可以在 ctx.Next() 之后通过 ctx.Status() 获取状态码
+type ResponseWriter interface {
+	http.ResponseWriter
+	http.Flusher
+	http.Hijacker
+	http.Pusher
+	// Status 响应的状态码, 还没有写响应时返回 200
+	Status() int
+	// Size 已经写入的响应体的字节数
+	Size() int
+	// Written 响应头是否已经发送
+	Written() bool
+	// Unwrap 返回被包装的 http.ResponseWriter, 用于 http.ResponseController
+	Unwrap() http.ResponseWriter
+}
+
+type responseWriter struct {
+	http.ResponseWriter
+	status  int
+	size    int
+	written bool
+
+	// 缓冲模式下, 状态码和响应体先记录下来, 调用链执行完之后再一次性发送
+	buffered  bool
+	headerSet bool
+	buf       bytes.Buffer
+}
+
+var _ ResponseWriter = (*responseWriter)(nil)
+
+func (w *responseWriter) reset(resp http.ResponseWriter) {
+	w.ResponseWriter = resp
+	w.status = http.StatusOK
+	w.size = 0
+	w.written = false
+	w.buffered = false
+	w.headerSet = false
+	if w.buf.Cap() > maxPooledBufferSize {
+		w.buf = bytes.Buffer{}
+	}
+	w.buf.Reset()
+}
+
+func (w *responseWriter) WriteHeader(statusCode int) {
+	// 和 net/http 一样, 只有第一次调用生效, 1xx 的状态码之后还可以再写最终的状态码
+	if w.written || w.headerSet {
+		return
+	}
+	if w.buffered && statusCode >= 200 {
+		w.status = statusCode
+		w.headerSet = true
+		return
+	}
+	if statusCode >= 100 && statusCode < 200 {
+		w.ResponseWriter.WriteHeader(statusCode)
+		return
+	}
+	w.status = statusCode
+	w.written = true
+	w.ResponseWriter.WriteHeader(statusCode)
+}
+
+func (w *responseWriter) Write(data []byte) (int, error) {
+	if w.buffered {
+		w.headerSet = true
+		n, err := w.buf.Write(data)
+		w.size += n
+		return n, err
+	}
+	if !w.written {
+		w.WriteHeader(http.StatusOK)
+	}
+	n, err := w.ResponseWriter.Write(data)
+	w.size += n
+	return n, err
+}
+
+func (w *responseWriter) Status() int {
+	return w.status
+}
+
+func (w *responseWriter) Size() int {
+	return w.size
+}
+
+func (w *responseWriter) Written() bool {
+	return w.written
+}
+
+func (w *responseWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
+
+// Flush 底层的 ResponseWriter 不支持时什么也不做
+// 缓冲模式下会先发送已经缓冲的内容, 之后的内容直接发送
+func (w *responseWriter) Flush() {
+	w.flushBuffer()
+	if flusher, ok := w.ResponseWriter.(http.Flusher); ok {
+		if !w.written {
+			w.WriteHeader(http.StatusOK)
+		}
+		flusher.Flush()
+	}
+}
+
+// Hijack 底层的 ResponseWriter 不支持时返回 http.ErrNotSupported
+func (w *responseWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	hijacker, ok := w.ResponseWriter.(http.Hijacker)
+	if !ok {
+		return nil, nil, http.ErrNotSupported
+	}
+	// 连接交给调用方之后, 缓冲的内容没有机会再发送
+	w.buffered = false
+	w.buf.Reset()
+	conn, rw, err := hijacker.Hijack()
+	if err == nil {
+		// 连接已经交给调用方, 不会再通过 ResponseWriter 写响应
+		w.written = true
+	}
+	return conn, rw, err
+}
+
+// Push 底层的 ResponseWriter 不支持时返回 http.ErrNotSupported
+func (w *responseWriter) Push(target string, opts *http.PushOptions) error {
+	if pusher, ok := w.ResponseWriter.(http.Pusher); ok {
+		return pusher.Push(target, opts)
+	}
+	return http.ErrNotSupported
+}
+
+// flushBuffer 发送缓冲的状态码和响应体, 并退出缓冲模式
+func (w *responseWriter) flushBuffer() {
+	if !w.buffered {
+		return
+	}
+	w.buffered = false
+	// handler 什么也没写, 交给 net/http 处理
+	if !w.headerSet {
+		return
+	}
+	// 中间件可能修改了响应体, 已经设置的 Content-Length 需要和实际的长度一致
+	if w.Header().Get("Content-Length") != "" {
+		w.Header().Set("Content-Length", strconv.Itoa(w.buf.Len()))
+	}
+	w.written = true
+	w.ResponseWriter.WriteHeader(w.status)
+	if w.buf.Len() > 0 {
+		n, _ := w.ResponseWriter.Write(w.buf.Bytes())
+		w.size = n
+	}
+	w.buf.Reset()
+}
@@ -0,0 +1,231 @@
+package server
+
+import (
+	"crypto/sha1"
+	"encoding/hex"
+	"net/http"
+	"net/http/httptest"
+	"path"
+	"runtime"
+	"strconv"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+// go test -v server/*.go -run TestContext_Status
+func TestContext_Status(t *testing.T) {
+	_, filePath, _, _ := runtime.Caller(0)
+	staticDir := path.Join(path.Dir(filePath), "testdata", "static")
+
+	type result struct {
+		status  int
+		size    int
+		written bool
+	}
+	var got result
+	serv := New(":8081")
+	serv.Use(func(ctx *Context) {
+		ctx.Next()
+		got = result{status: ctx.Status(), size: ctx.Size(), written: ctx.Written()}
+	})
+	serv.Get("/string", func(ctx *Context) {
+		ctx.WriteString(http.StatusCreated, []byte("created"))
+	})
+	serv.Get("/write", func(ctx *Context) {
+		_, _ = ctx.Resp.Write([]byte("ok"))
+		// 响应头已经发送, 之后的状态码不会生效
+		ctx.Resp.WriteHeader(http.StatusInternalServerError)
+	})
+	serv.Get("/nothing", func(ctx *Context) {})
+	serv.Get("/std", WrapHandler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "teapot", http.StatusTeapot)
+	})))
+	serv.ServeStaticDir("/static", staticDir)
+
+	testCases := []struct {
+		name string
+		path string
+		want result
+	}{
+		{
+			name: "write string",
+			path: "/string",
+			want: result{status: http.StatusCreated, size: 7, written: true},
+		},
+		{
+			name: "implicit status",
+			path: "/write",
+			want: result{status: http.StatusOK, size: 2, written: true},
+		},
+		{
+			name: "nothing written",
+			path: "/nothing",
+			want: result{status: http.StatusOK},
+		},
+		{
+			name: "http.Handler",
+			path: "/std",
+			want: result{status: http.StatusTeapot, size: 7, written: true},
+		},
+		{
+			name: "not found",
+			path: "/unknown",
+			want: result{status: http.StatusNotFound, size: 9, written: true},
+		},
+		{
+			name: "static file not exists",
+			path: "/static/unknown.jpeg",
+			want: result{status: http.StatusInternalServerError, size: 15, written: true},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			got = result{}
+			resp := httptest.NewRecorder()
+			serv.ServeHTTP(resp, httptest.NewRequest(http.MethodGet, tc.path, nil))
+			require.Equal(t, tc.want, got)
+			require.Equal(t, tc.want.size, resp.Body.Len())
+		})
+	}
+}
+
+// go test -v server/*.go -run TestResponseWriter_Interfaces
+func TestResponseWriter_Interfaces(t *testing.T) {
+	serv := New(":8081")
+	serv.Get("/stream", func(ctx *Context) {
+		flusher, ok := ctx.Resp.(http.Flusher)
+		require.True(t, ok)
+		_, _ = ctx.Resp.Write([]byte("chunk"))
+		flusher.Flush()
+
+		_, _, err := ctx.Resp.(http.Hijacker).Hijack()
+		require.ErrorIs(t, err, http.ErrNotSupported)
+		require.ErrorIs(t, ctx.Resp.(http.Pusher).Push("/app.js", nil), http.ErrNotSupported)
+		// http.ResponseController 通过 Unwrap 找到底层的 ResponseWriter
+		require.NoError(t, http.NewResponseController(ctx.Resp).Flush())
+	})
+
+	resp := httptest.NewRecorder()
+	serv.ServeHTTP(resp, httptest.NewRequest(http.MethodGet, "/stream", nil))
+	require.True(t, resp.Flushed)
+	require.Equal(t, "chunk", resp.Body.String())
+}
+
+// go test -v server/*.go -run TestContext_JSONHeader
+func TestContext_JSONHeader(t *testing.T) {
+	serv := New(":8081")
+	serv.Get("/user", func(ctx *Context) {
+		ctx.JSON(http.StatusCreated, map[string]any{"name": "jungle"})
+	})
+
+	resp := httptest.NewRecorder()
+	serv.ServeHTTP(resp, httptest.NewRequest(http.MethodGet, "/user", nil))
+	// ResponseRecorder 在 WriteHeader 时记录响应头, 之后设置的响应头不会出现在结果中
+	result := resp.Result()
+	require.Equal(t, http.StatusCreated, result.StatusCode)
+	require.Equal(t, "application/json", result.Header.Get("Content-Type"))
+	require.Equal(t, "17", result.Header.Get("Content-Length"))
+}
+
+// go test -v server/*.go -run TestContext_BufferResponse
+func TestContext_BufferResponse(t *testing.T) {
+
+	etag := func(body []byte) string {
+		sum := sha1.Sum(body)
+		return `"` + hex.EncodeToString(sum[:]) + `"`
+	}
+	// 给响应加上 ETag, 错误时统一包装响应体
+	rewrite := func(ctx *Context) {
+		ctx.BufferResponse()
+		ctx.Next()
+		if ctx.Status() >= http.StatusBadRequest {
+			ctx.Resp.Header().Set("Content-Type", "application/json")
+			ctx.SetResponseBody([]byte(`{"code":` + strconv.Itoa(ctx.Status()) + `,"msg":"` + string(ctx.ResponseBody()) + `"}`))
+			ctx.SetStatus(http.StatusOK)
+			return
+		}
+		ctx.Resp.Header().Set("ETag", etag(ctx.ResponseBody()))
+	}
+
+	testCases := []struct {
+		name       string
+		opts       []Option
+		mws        []HandleFunc
+		path       string
+		wantCode   int
+		wantBody   string
+		wantHeader http.Header
+	}{
+		{
+			name:     "etag",
+			mws:      []HandleFunc{rewrite},
+			path:     "/user",
+			wantCode: http.StatusOK,
+			wantBody: `{"name":"jungle"}`,
+			wantHeader: http.Header{
+				"Content-Type":   []string{"application/json"},
+				"Content-Length": []string{"17"},
+				"Etag":           []string{etag([]byte(`{"name":"jungle"}`))},
+			},
+		},
+		{
+			name:     "error envelope",
+			mws:      []HandleFunc{rewrite},
+			path:     "/unknown",
+			wantCode: http.StatusOK,
+			wantBody: `{"code":404,"msg":"NOT FOUND"}`,
+			wantHeader: http.Header{
+				"Content-Type": []string{"application/json"},
+			},
+		},
+		{
+			name:     "buffer all responses",
+			opts:     []Option{WithBufferedResponse(true)},
+			mws:      []HandleFunc{func(ctx *Context) { ctx.Next(); ctx.SetStatus(http.StatusAccepted) }},
+			path:     "/user",
+			wantCode: http.StatusAccepted,
+			wantBody: `{"name":"jungle"}`,
+		},
+		{
+			name:     "flush stops buffering",
+			opts:     []Option{WithBufferedResponse(true)},
+			mws:      []HandleFunc{func(ctx *Context) { ctx.Next(); ctx.SetStatus(http.StatusAccepted) }},
+			path:     "/stream",
+			wantCode: http.StatusOK,
+			wantBody: "chunk1chunk2",
+		},
+		{
+			name:     "not buffered",
+			mws:      []HandleFunc{func(ctx *Context) { ctx.Next(); ctx.SetResponseBody([]byte("ignored")) }},
+			path:     "/user",
+			wantCode: http.StatusOK,
+			wantBody: `{"name":"jungle"}`,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			serv := New(":8081", tc.opts...)
+			serv.Use(tc.mws...)
+			serv.Get("/user", func(ctx *Context) {
+				ctx.JSON(http.StatusOK, map[string]any{"name": "jungle"})
+			})
+			serv.Get("/stream", func(ctx *Context) {
+				_, _ = ctx.Resp.Write([]byte("chunk1"))
+				ctx.Resp.(http.Flusher).Flush()
+				_, _ = ctx.Resp.Write([]byte("chunk2"))
+			})
+
+			resp := httptest.NewRecorder()
+			serv.ServeHTTP(resp, httptest.NewRequest(http.MethodGet, tc.path, nil))
+			result := resp.Result()
+			require.Equal(t, tc.wantCode, result.StatusCode)
+			require.Equal(t, tc.wantBody, resp.Body.String())
+			for key := range tc.wantHeader {
+				require.Equal(t, tc.wantHeader.Get(key), result.Header.Get(key))
+			}
+		})
+	}
+}
@@ -0,0 +1,23 @@
+//go:build !unix
+
+package server
+
+import (
+	"context"
+	"net"
+)
+
+// Restart 平滑重启只支持 unix 系统
+func (s *HTTPServer) Restart(ctx context.Context) error {
+	return ErrRestartNotSupported
+}
+
+func (s *HTTPServer) spawnChild(ctx context.Context) error {
+	return ErrRestartNotSupported
+}
+
+func inheritedListener() (net.Listener, error) {
+	return nil, nil
+}
+
+func notifyReady() {}
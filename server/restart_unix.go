@@ -0,0 +1,135 @@
+//go:build unix
+
+package server
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"os"
+	"os/exec"
+	"strconv"
+	"strings"
+)
+
+const (
+	// envListenFD 子进程继承的 listener 的 fd
+	envListenFD = "JUNGLE_LISTEN_FD"
+	// envReadyFD 子进程开始处理请求之后, 通过这个 fd 通知父进程
+	envReadyFD = "JUNGLE_READY_FD"
+)
+
+// Restart 平滑重启: 把正在监听的 socket 传给新启动的子进程, 等子进程开始处理请求之后
+// 当前进程停止接收新的连接, 处理完剩余的请求之后执行退出钩子, 不会像 Shutdown 一样返回 503
+// ctx 同时控制等待子进程和退出的时间, 子进程使用相同的可执行文件和参数启动, Start 时会自动使用继承的 socket
+func (s *HTTPServer) Restart(ctx context.Context) error {
+	if err := s.spawnChild(ctx); err != nil {
+		return err
+	}
+	return s.shutdown(ctx, false)
+}
+
+// spawnChild 启动子进程并等待它就绪, 失败时当前进程继续处理请求
+func (s *HTTPServer) spawnChild(ctx context.Context) error {
+	filer, ok := s.listener.(interface{ File() (*os.File, error) })
+	if !ok {
+		return fmt.Errorf("%w: listener %T has no file descriptor", ErrRestartNotSupported, s.listener)
+	}
+	// File 返回的是 dup 之后的 fd, 关闭不影响当前进程的 listener
+	lf, err := filer.File()
+	if err != nil {
+		return err
+	}
+	defer lf.Close()
+	readyR, readyW, err := os.Pipe()
+	if err != nil {
+		return err
+	}
+	defer readyR.Close()
+
+	executable, err := os.Executable()
+	if err != nil {
+		_ = readyW.Close()
+		return err
+	}
+	cmd := exec.Command(executable, os.Args[1:]...)
+	cmd.Stdin, cmd.Stdout, cmd.Stderr = os.Stdin, os.Stdout, os.Stderr
+	// ExtraFiles 在子进程中的 fd 从 3 开始
+	cmd.ExtraFiles = []*os.File{lf, readyW}
+	cmd.Env = append(restartEnv(os.Environ()), envListenFD+"=3", envReadyFD+"=4")
+	err = cmd.Start()
+	// 父进程不再需要写端, 子进程退出时读端才能读到 EOF
+	_ = readyW.Close()
+	if err != nil {
+		return err
+	}
+
+	readyCh := make(chan error, 1)
+	go func() {
+		buf := make([]byte, 1)
+		_, err := readyR.Read(buf)
+		readyCh <- err
+	}()
+	select {
+	case err = <-readyCh:
+		if err != nil {
+			_ = cmd.Process.Kill()
+			_ = cmd.Wait()
+			return fmt.Errorf("child process exited before ready: %w", err)
+		}
+	case <-ctx.Done():
+		_ = cmd.Process.Kill()
+		_ = cmd.Wait()
+		return ctx.Err()
+	}
+	// 子进程独立运行, 不需要等待它退出
+	return cmd.Process.Release()
+}
+
+// restartEnv 去掉从父进程继承的重启相关的环境变量
+func restartEnv(environ []string) []string {
+	res := make([]string, 0, len(environ))
+	for _, env := range environ {
+		if strings.HasPrefix(env, envListenFD+"=") || strings.HasPrefix(env, envReadyFD+"=") {
+			continue
+		}
+		res = append(res, env)
+	}
+	return res
+}
+
+// inheritedListener 返回父进程传过来的 listener, 不是重启启动的进程返回 nil
+func inheritedListener() (net.Listener, error) {
+	fd, ok := envFD(envListenFD)
+	if !ok {
+		return nil, nil
+	}
+	f := os.NewFile(fd, "listener")
+	defer f.Close()
+	return net.FileListener(f)
+}
+
+// notifyReady 通知父进程已经开始处理请求
+func notifyReady() {
+	fd, ok := envFD(envReadyFD)
+	if !ok {
+		return
+	}
+	f := os.NewFile(fd, "ready")
+	defer f.Close()
+	_, _ = f.Write([]byte{1})
+}
+
+// envFD 读取并清除环境变量中的 fd, 保证只会被使用一次
+func envFD(key string) (uintptr, bool) {
+	val := os.Getenv(key)
+	if val == "" {
+		return 0, false
+	}
+	_ = os.Unsetenv(key)
+	fd, err := strconv.Atoi(val)
+	if err != nil || fd < 0 {
+		return 0, false
+	}
+	return uintptr(fd), true
+}
@@ -0,0 +1,192 @@
+//go:build unix
+
+package server
+
+import (
+	"context"
+	"io"
+	"net"
+	"net/http"
+	"os"
+	"strconv"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+// TestMain 重启时会用相同的参数启动测试程序, 子进程只负责处理请求, 不运行测试
+func TestMain(m *testing.M) {
+	if os.Getenv(envListenFD) != "" {
+		os.Exit(runRestartChild())
+	}
+	os.Exit(m.Run())
+}
+
+// envTestChildListener 子进程创建 listener 的方式
+const envTestChildListener = "JUNGLE_TEST_CHILD_LISTENER"
+
+// go test -v server/*.go -run TestHTTPServer_Restart
+func TestHTTPServer_Restart(t *testing.T) {
+	testCases := []struct {
+		name          string
+		childListener string
+	}{
+		{
+			name: "listen addr",
+		},
+		{
+			// 子进程通过 WithListener 传入了其他的 listener, 仍然使用继承的 listener
+			name:          "with listener",
+			childListener: "preset",
+		},
+		{
+			name:          "with listener from Listen",
+			childListener: "listen",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Setenv(envTestChildListener, tc.childListener)
+			l, err := net.Listen("tcp", "127.0.0.1:0")
+			require.NoError(t, err)
+			addr := l.Addr().String()
+			serv := New(addr, WithListener(l), WithRestartSignals(syscall.SIGUSR2))
+			serv.Get("/pid", pidHandler)
+			errCh := make(chan error, 1)
+			go func() {
+				errCh <- serv.Run(context.Background())
+			}()
+			// 请求成功说明 Run 已经开始监听重启信号
+			require.Equal(t, os.Getpid(), getPid(t, addr))
+
+			require.NoError(t, syscall.Kill(os.Getpid(), syscall.SIGUSR2))
+			select {
+			case err := <-errCh:
+				require.NoError(t, err)
+			case <-time.After(time.Second * 30):
+				t.Fatal("parent not stopped after restart")
+			}
+
+			// 父进程退出之后, 同一个地址由子进程继续处理请求
+			childPid := getPid(t, addr)
+			require.NotEqual(t, os.Getpid(), childPid)
+			require.NoError(t, syscall.Kill(childPid, syscall.SIGTERM))
+		})
+	}
+}
+
+// go test -v server/*.go -run TestHTTPServer_RestartWithSlowRequest
+func TestHTTPServer_RestartWithSlowRequest(t *testing.T) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	addr := l.Addr().String()
+	serv := New(addr, WithListener(l), WithRestartSignals(syscall.SIGUSR2))
+	serv.Get("/pid", pidHandler)
+	entered, release := make(chan struct{}), make(chan struct{})
+	serv.Get("/slow", func(ctx *Context) {
+		close(entered)
+		<-release
+		ctx.WriteString(http.StatusOK, []byte("slow"))
+	})
+	errCh := make(chan error, 1)
+	go func() {
+		errCh <- serv.Run(context.Background())
+	}()
+	require.Equal(t, os.Getpid(), getPid(t, addr))
+
+	slowCh := make(chan int, 1)
+	go func() {
+		resp, err := http.Get("http://" + addr + "/slow")
+		if err != nil {
+			slowCh <- 0
+			return
+		}
+		resp.Body.Close()
+		slowCh <- resp.StatusCode
+	}()
+	<-entered
+	require.NoError(t, syscall.Kill(os.Getpid(), syscall.SIGUSR2))
+
+	// 父进程等待慢请求的过程中, 新的连接都由子进程处理, 不会收到 503
+	childPid, childResponses := 0, 0
+	for childResponses < 20 {
+		pid := getPid(t, addr)
+		if pid != os.Getpid() {
+			childPid = pid
+			childResponses++
+		}
+	}
+	select {
+	case <-errCh:
+		t.Fatal("parent stopped before slow request finished")
+	default:
+	}
+
+	close(release)
+	require.Equal(t, http.StatusOK, <-slowCh)
+	select {
+	case err := <-errCh:
+		require.NoError(t, err)
+	case <-time.After(time.Second * 30):
+		t.Fatal("parent not stopped after restart")
+	}
+	require.NoError(t, syscall.Kill(childPid, syscall.SIGTERM))
+}
+
+// go test -v server/*.go -run TestHTTPServer_RestartNotSupported
+func TestHTTPServer_RestartNotSupported(t *testing.T) {
+	serv := New(":8081", WithListener(&noFileListener{}))
+	require.ErrorIs(t, serv.Restart(context.Background()), ErrRestartNotSupported)
+}
+
+func runRestartChild() int {
+	var opts []Option
+	switch os.Getenv(envTestChildListener) {
+	case "preset":
+		l, err := net.Listen("tcp", "127.0.0.1:0")
+		if err != nil {
+			return 1
+		}
+		opts = append(opts, WithListener(l))
+	case "listen":
+		l, err := Listen("tcp", "127.0.0.1:0")
+		if err != nil {
+			return 1
+		}
+		opts = append(opts, WithListener(l))
+	}
+	serv := New("127.0.0.1:0", opts...)
+	serv.Get("/pid", pidHandler)
+	ctx, stop := SignalContext(context.Background())
+	defer stop()
+	if err := serv.Run(ctx); err != nil {
+		return 1
+	}
+	return 0
+}
+
+func pidHandler(ctx *Context) {
+	ctx.WriteString(http.StatusOK, []byte(strconv.Itoa(os.Getpid())))
+}
+
+func getPid(t *testing.T, addr string) int {
+	client := &http.Client{
+		Transport: &http.Transport{DisableKeepAlives: true},
+	}
+	resp, err := client.Get("http://" + addr + "/pid")
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	body, err := io.ReadAll(resp.Body)
+	require.NoError(t, err)
+	pid, err := strconv.Atoi(string(body))
+	require.NoError(t, err)
+	return pid
+}
+
+// noFileListener 不能获取 fd 的 listener
+type noFileListener struct {
+	net.Listener
+}
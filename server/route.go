@@ -0,0 +1,146 @@
+package server
+
+import (
+	"fmt"
+	"net/http"
+	"reflect"
+	"runtime"
+	"sort"
+	"strings"
+	"text/tabwriter"
+)
+
+// Route 已注册的路由
+type Route struct {
+	serv   *HTTPServer
+	host   string
+	method string
+	path   string
+}
+
+// Name 给路由命名, 之后可以通过 HTTPServer.URLFor 反向生成 URL
+func (r *Route) Name(name string) *Route {
+	err := r.serv.updateRoutes(func(t *routeTable) error {
+		rt := t.routerOf(r.host)
+		var n *node
+		if rt != nil {
+			n = rt.lookup(r.method, r.path)
+		}
+		if n == nil {
+			return newRouteError(ErrRouteNotFound, r.method, r.path, "")
+		}
+		rt.SetName(n, name)
+		return nil
+	})
+	if err != nil {
+		panic(err)
+	}
+	return r
+}
+
+// RouteInfo 路由信息, 用来打印路由表和做契约测试
+type RouteInfo struct {
+	// Host 为空表示默认的路由树
+	Host    string
+	Method  string
+	Pattern string
+	Name    string
+	// Handler 最终处理请求的 handler 的函数名
+	Handler string
+	// Middlewares 中间件的数量, 包括全局中间件和分组中间件
+	Middlewares int
+	// Predicates 路由的匹配条件, 没有条件时为 nil
+	Predicates []string
+}
+
+// Routes 返回所有已注册的路由, 按 Pattern 和 Method 排序
+func (r *router) Routes() []RouteInfo {
+	routes := make([]RouteInfo, 0)
+	for method, root := range r.tree {
+		root.walk(func(n *node) {
+			if len(n.handlerChains) > 0 {
+				routes = append(routes, newRouteInfo(method, n, n.handlerChains, nil))
+			}
+			for _, v := range n.variants {
+				routes = append(routes, newRouteInfo(method, n, v.handlerChains, v.predicates))
+			}
+		})
+	}
+	// 同一个路由的多个匹配条件保持注册顺序
+	sort.SliceStable(routes, func(i, j int) bool {
+		if routes[i].Pattern != routes[j].Pattern {
+			return routes[i].Pattern < routes[j].Pattern
+		}
+		return routes[i].Method < routes[j].Method
+	})
+	return routes
+}
+
+func newRouteInfo(method string, n *node, handlerChains []HandleFunc, predicates []Predicate) RouteInfo {
+	info := RouteInfo{
+		Method:      method,
+		Pattern:     n.matchedPath,
+		Name:        n.name,
+		Handler:     funcName(handlerChains[len(handlerChains)-1]),
+		Middlewares: len(handlerChains) - 1,
+	}
+	for _, p := range predicates {
+		info.Predicates = append(info.Predicates, p.String())
+	}
+	return info
+}
+
+// walk 深度优先遍历 n 的子树
+func (n *node) walk(fn func(n *node)) {
+	fn(n)
+	for _, child := range n.children {
+		child.walk(fn)
+	}
+	for _, child := range n.regChildren {
+		child.walk(fn)
+	}
+	if n.paramChild != nil {
+		n.paramChild.walk(fn)
+	}
+	if n.starChild != nil {
+		n.starChild.walk(fn)
+	}
+}
+
+func funcName(fn HandleFunc) string {
+	f := runtime.FuncForPC(reflect.ValueOf(fn).Pointer())
+	if f == nil {
+		return "unknown"
+	}
+	return f.Name()
+}
+
+// Routes 返回所有已注册的路由, 默认路由树在前, host 路由树按注册顺序在后
+func (s *HTTPServer) Routes() []RouteInfo {
+	t := s.table()
+	routes := t.router.Routes()
+	for _, pattern := range t.hosts.patterns {
+		for _, route := range t.routerOf(pattern).Routes() {
+			route.Host = pattern
+			routes = append(routes, route)
+		}
+	}
+	return routes
+}
+
+// RoutesHandler 以表格的形式输出路由表, 用于调试, 例如:
+//
+//	serv.Get("/debug/routes", serv.RoutesHandler())
+func (s *HTTPServer) RoutesHandler() HandleFunc {
+	return func(ctx *Context) {
+		var sb strings.Builder
+		w := tabwriter.NewWriter(&sb, 0, 4, 2, ' ', 0)
+		_, _ = fmt.Fprintln(w, "HOST\tMETHOD\tPATTERN\tNAME\tMIDDLEWARES\tHANDLER\tPREDICATES")
+		for _, route := range s.Routes() {
+			_, _ = fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%d\t%s\t%s\n", route.Host, route.Method, route.Pattern, route.Name, route.Middlewares, route.Handler, strings.Join(route.Predicates, " "))
+		}
+		_ = w.Flush()
+		ctx.Resp.Header().Set("Content-Type", "text/plain; charset=utf-8")
+		ctx.WriteString(http.StatusOK, []byte(sb.String()))
+	}
+}
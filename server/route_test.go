@@ -0,0 +1,156 @@
+package server
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+// go test -v server/*.go -run TestHTTPServer_URLFor
+func TestHTTPServer_URLFor(t *testing.T) {
+
+	var mockHandler HandleFunc = func(ctx *Context) {}
+	serv := New(":8081")
+	serv.Get("/", mockHandler).Name("index")
+	serv.Get("/user/:id/fav/:fid", mockHandler).Name("user-fav")
+	serv.Get(`/order/:id(\d+)`, mockHandler).Name("order")
+	serv.Get("/docs/", mockHandler).Name("docs")
+	serv.Get("/static/*", mockHandler).Name("static")
+	serv.Get("/assets/*filepath", mockHandler).Name("assets")
+	serv.Group("/api/v1").Post("/login", mockHandler).Name("login")
+
+	testCases := []struct {
+		name    string
+		route   string
+		params  []any
+		wantURL string
+		wantErr error
+	}{
+		{
+			name:    "root",
+			route:   "index",
+			wantURL: "/",
+		},
+		{
+			name:    "path params",
+			route:   "user-fav",
+			params:  []any{"id", 1, "fid", "a b"},
+			wantURL: "/user/1/fav/a%20b",
+		},
+		{
+			name:    "regexp param",
+			route:   "order",
+			params:  []any{"id", 12},
+			wantURL: "/order/12",
+		},
+		{
+			name:    "regexp param not matched",
+			route:   "order",
+			params:  []any{"id", "abc"},
+			wantErr: ErrInvalidURLParams,
+		},
+		{
+			name:    "trailing slash",
+			route:   "docs",
+			wantURL: "/docs/",
+		},
+		{
+			name:    "star",
+			route:   "static",
+			params:  []any{"*", "css/main.css"},
+			wantURL: "/static/css/main.css",
+		},
+		{
+			name:    "catch-all",
+			route:   "assets",
+			params:  []any{"filepath", "css/main app.css"},
+			wantURL: "/assets/css/main%20app.css",
+		},
+		{
+			name:    "group route",
+			route:   "login",
+			wantURL: "/api/v1/login",
+		},
+		{
+			name:    "missing param",
+			route:   "user-fav",
+			params:  []any{"id", 1},
+			wantErr: ErrMissingURLParam,
+		},
+		{
+			name:    "params not in pairs",
+			route:   "user-fav",
+			params:  []any{"id"},
+			wantErr: ErrInvalidURLParams,
+		},
+		{
+			name:    "name not found",
+			route:   "unknown",
+			wantErr: ErrRouteNameNotFound,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			u, err := serv.URLFor(tc.route, tc.params...)
+			require.True(t, errors.Is(err, tc.wantErr), err)
+			require.Equal(t, tc.wantURL, u)
+		})
+	}
+
+	require.Panics(t, func() {
+		serv.AddRoute(http.MethodPost, "/user/:id", mockHandler).Name("user-fav")
+	})
+}
+
+func getUser(ctx *Context) {}
+
+// go test -v server/*.go -run TestHTTPServer_Routes
+func TestHTTPServer_Routes(t *testing.T) {
+
+	var mockMiddleware HandleFunc = func(ctx *Context) { ctx.Next() }
+	serv := New(":8081")
+	serv.Use(mockMiddleware)
+	serv.Get("/user/:id", getUser, mockMiddleware).Name("user")
+	serv.Post("/user/:id", getUser)
+	serv.Group("/api", mockMiddleware).Delete(`/order/:id(\d+)`, getUser)
+	serv.Get("/static/*", getUser)
+	serv.Get("/debug/routes", serv.RoutesHandler())
+
+	routes := serv.Routes()
+	require.Len(t, routes, 5)
+	require.Equal(t, RouteInfo{
+		Method:      http.MethodDelete,
+		Pattern:     `/api/order/:id(\d+)`,
+		Handler:     "jungle/server.getUser",
+		Middlewares: 2,
+	}, routes[0])
+	require.Equal(t, "/debug/routes", routes[1].Pattern)
+	require.Equal(t, "/static/*", routes[2].Pattern)
+	require.Equal(t, RouteInfo{
+		Method:      http.MethodGet,
+		Pattern:     "/user/:id",
+		Name:        "user",
+		Handler:     "jungle/server.getUser",
+		Middlewares: 2,
+	}, routes[3])
+	require.Equal(t, RouteInfo{
+		Method:      http.MethodPost,
+		Pattern:     "/user/:id",
+		Handler:     "jungle/server.getUser",
+		Middlewares: 1,
+	}, routes[4])
+
+	resp := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodGet, "/debug/routes", nil)
+	serv.ServeHTTP(resp, req)
+	require.Equal(t, http.StatusOK, resp.Code)
+	lines := strings.Split(strings.TrimSpace(resp.Body.String()), "\n")
+	require.Len(t, lines, 6)
+	require.True(t, strings.HasPrefix(lines[0], "HOST"))
+	require.Contains(t, lines[4], "user")
+}
@@ -1,20 +1,29 @@
 package server
 
 import (
-	"log"
+	"fmt"
 	"net/http"
 	"net/url"
+	"regexp"
+	"sort"
 	"strings"
 )
 
 type router struct {
 	tree            map[string]*node
 	supportedMethod map[string]bool
+	// 静态路由忽略大小写
+	ignoreCase bool
+	// 路由名字 => 叶子节点
+	names map[string]*node
+	// 校验模式, 重复注册和路径参数名字不一致时报错
+	strict bool
 }
 
 func newRouter() *router {
 	return &router{
-		tree: make(map[string]*node),
+		tree:  make(map[string]*node),
+		names: make(map[string]*node),
 		supportedMethod: map[string]bool{
 			http.MethodGet:     true,
 			http.MethodHead:    true,
@@ -29,15 +38,87 @@ func newRouter() *router {
 	}
 }
 
-func (r *router) AddRoute(method string, path string, servMiddlewares []HandleFunc, handler HandleFunc, middlewares ...HandleFunc) {
+// clone 深拷贝路由树, 用于在副本上修改路由
+func (r *router) clone() *router {
+	c := &router{
+		tree:            make(map[string]*node, len(r.tree)),
+		supportedMethod: r.supportedMethod,
+		ignoreCase:      r.ignoreCase,
+		names:           make(map[string]*node, len(r.names)),
+		strict:          r.strict,
+	}
+	for method, root := range r.tree {
+		c.tree[method] = root.clone()
+		c.tree[method].walk(func(n *node) {
+			if n.name != "" {
+				c.names[n.name] = n
+			}
+		})
+	}
+	return c
+}
+
+// lookup 返回 method 下注册为 path 的叶子节点
+func (r *router) lookup(method string, path string) *node {
+	root, ok := r.tree[method]
+	if !ok {
+		return nil
+	}
+	var found *node
+	root.walk(func(n *node) {
+		if found == nil && n.isLeaf() && n.matchedPath == path {
+			found = n
+		}
+	})
+	return found
+}
+
+// removeRoute 删除路由, 包括带匹配条件的路由, 并清理不再使用的节点
+func (r *router) removeRoute(method string, path string) error {
+	n := r.lookup(method, path)
+	if n == nil {
+		return newRouteError(ErrRouteNotFound, method, path, "")
+	}
+	if n.name != "" {
+		delete(r.names, n.name)
+		n.name = ""
+	}
+	n.matchedPath = ""
+	n.handlerChains = nil
+	n.variants = nil
+	if r.tree[method].prune() {
+		delete(r.tree, method)
+	}
+	return nil
+}
+
+// AddRoute 添加路由, 返回路由对应的叶子节点, 和已注册的路由冲突时 panic
+// 默认只检查 * 和路径参数的冲突, 开启校验模式后还会检查重复注册和路径参数名字不一致
+func (r *router) AddRoute(method string, path string, servMiddlewares []HandleFunc, handler HandleFunc, middlewares ...HandleFunc) *node {
+	n, err := r.addRoute(method, path, r.strict, nil, servMiddlewares, handler, middlewares...)
+	if err != nil {
+		panic(err)
+	}
+	return n
+}
+
+// AddRouteE 添加路由, 总是开启校验模式, 冲突时返回 *RouteError 而不是 panic
+func (r *router) AddRouteE(method string, path string, servMiddlewares []HandleFunc, handler HandleFunc, middlewares ...HandleFunc) (*node, error) {
+	return r.addRoute(method, path, true, nil, servMiddlewares, handler, middlewares...)
+}
+
+func (r *router) addRoute(method string, path string, strict bool, predicates []Predicate, servMiddlewares []HandleFunc, handler HandleFunc, middlewares ...HandleFunc) (*node, error) {
+	// 先校验, 避免冲突时路由树被修改了一半
+	if err := r.checkRoute(method, path, strict, predicates); err != nil {
+		return nil, err
+	}
 
 	// middle 和 handlers 组合
-	handlerChain := append(servMiddlewares, middlewares...)
+	// 重新分配, 避免和 servMiddlewares 共享底层数组, 导致不同路由的调用链互相覆盖
+	handlerChain := make([]HandleFunc, 0, len(servMiddlewares)+len(middlewares)+1)
+	handlerChain = append(handlerChain, servMiddlewares...)
+	handlerChain = append(handlerChain, middlewares...)
 	handlerChain = append(handlerChain, handler)
-	// validate the method must be one of the http.Method
-	if !r.supportedMethod[method] {
-		log.Fatalf("method: %s not supported", method)
-	}
 	root, ok := r.tree[method]
 	if !ok {
 		root = &node{
@@ -46,219 +127,527 @@ func (r *router) AddRoute(method string, path string, servMiddlewares []HandleFu
 		}
 		r.tree[method] = root
 	}
-	// 切割path
-	segs := strings.Split(path, "/")
+	// 切割path, 去除有问题的seg
+	segs := splitPath(path)
 	cur := root
-	total := len(segs)
-	for idx, seg := range segs {
-		// 去除有问题的seg
-		if seg == "" {
-			continue
-		}
-		// 如果是 *
-		if seg == "*" {
-			// 不允许同时存在 * 和 :
-			if cur.paramChild != nil {
-				panic("already a param child exists")
-			}
-			next := &node{
-				path:     seg,
-				children: make([]*node, 0),
-			}
+	for idx := 0; idx < len(segs); {
+		seg := segs[idx]
+		// 如果是 * 或者 *name
+		if strings.HasPrefix(seg, "*") {
 			if cur.starChild == nil {
-				cur.starChild = next
-			}
-			if total == idx+1 {
-				cur.starChild.matchedPath = path
-				cur.starChild.handlerChains = handlerChain
+				cur.starChild = &node{
+					path:     seg,
+					children: make([]*node, 0),
+				}
 			}
 			cur = cur.starChild
+			idx++
 			continue
 		} else if strings.HasPrefix(seg, ":") {
+			name, regExpr, _ := parseParam(seg)
+			idx++
 
-			// 不允许同时存在 * 和 :
-			if cur.starChild != nil {
-				panic("already a star child exists")
-			}
-			next := &node{
-				path: strings.Trim(seg, ":"),
-				// path:     seg,
-				children: make([]*node, 0),
+			// 带约束的路径参数, 不匹配时可以继续尝试其他节点, 所以允许和 * 共存
+			if regExpr != nil {
+				var next *node
+				for _, child := range cur.regChildren {
+					if child.path == name && child.regExpr.String() == regExpr.String() {
+						next = child
+						break
+					}
+				}
+				if next == nil {
+					next = &node{
+						path:     name,
+						children: make([]*node, 0),
+						regExpr:  regExpr,
+					}
+					cur.regChildren = append(cur.regChildren, next)
+				}
+				cur = next
+				continue
 			}
+
 			if cur.paramChild == nil {
-				cur.paramChild = next
-			}
-			if total == idx+1 {
-				cur.paramChild.matchedPath = path
-				cur.paramChild.handlerChains = handlerChain
+				cur.paramChild = &node{
+					path:     name,
+					children: make([]*node, 0),
+				}
 			}
 			cur = cur.paramChild
 			continue
 		}
 
-		// 应该和当前节点的children来比较
-		insertIndex := -1
-		continueIndex := -1
-		children := cur.children
-		for idx, child := range children {
-			// 大于需要插入
-			if child.path > seg {
-				insertIndex = idx
-				break
-			} else if child.path == seg {
-				// 相等, 需要 找他的children,将cur设置成他就可以了
-				continueIndex = idx
-				break
-			}
-		}
-		if continueIndex >= 0 {
-			// 下一个
-			cur = cur.children[continueIndex]
-			// 这个地方可能会忽略掉,导致出问题.
-			if total == idx+1 {
-				cur.matchedPath = path
-				cur.handlerChains = handlerChain
-			}
-			continue
+		// 连续的静态 seg 压缩到同一个节点
+		end := idx + 1
+		for end < len(segs) && isStaticSeg(segs[end]) {
+			end++
 		}
+		cur = cur.insertStatic(segs[idx:end])
+		idx = end
+	}
 
-		// 需要insert
-		if insertIndex >= 0 {
+	// 是叶子节点,绑定调用链
+	cur.matchedPath = path
+	if len(predicates) == 0 {
+		cur.handlerChains = handlerChain
+		return cur, nil
+	}
+	cur.setVariant(&variant{
+		predicates:    append([]Predicate{}, predicates...),
+		key:           predicateKey(predicates),
+		handlerChains: handlerChain,
+	})
+	return cur, nil
+}
 
-			// 创建
+// insertStatic 插入连续的静态 seg, 返回最后一个 seg 对应的节点
+// 和已有的子节点只有部分 seg 相同时, 需要把子节点分裂成公共前缀和剩余部分两个节点
+func (n *node) insertStatic(segs []string) *node {
+	cur := n
+	for len(segs) > 0 {
+		idx, child := cur.childIndex(segs[0])
+		if child == nil {
 			next := &node{
-				path:     seg,
+				path:     strings.Join(segs, "/"),
 				children: make([]*node, 0),
 			}
+			// 保持 children 有序, 查找的时候可以二分查找
+			cur.children = append(cur.children, nil)
+			copy(cur.children[idx+1:], cur.children[idx:])
+			cur.children[idx] = next
+			return next
+		}
 
-			// 是叶子节点,绑定调用链
-			if total == idx+1 {
-				next.matchedPath = path
-				next.handlerChains = handlerChain
+		childSegs := strings.Split(child.path, "/")
+		common := 0
+		for common < len(childSegs) && common < len(segs) && childSegs[common] == segs[common] {
+			common++
+		}
+		if common < len(childSegs) {
+			// 分裂, child 保留剩余部分, 这样已经注册的叶子节点不会变
+			parent := &node{
+				path:     strings.Join(childSegs[:common], "/"),
+				children: []*node{child},
 			}
-			if insertIndex != 0 {
-				insertIndex = insertIndex - 1
+			child.path = strings.Join(childSegs[common:], "/")
+			cur.children[idx] = parent
+			child = parent
+		}
+		cur = child
+		segs = segs[common:]
+	}
+	return cur
+}
+
+// childIndex 二分查找第一个 seg 等于 seg 的静态子节点
+// 找不到时 child 为 nil, idx 为应该插入的位置
+func (n *node) childIndex(seg string) (idx int, child *node) {
+	idx = sort.Search(len(n.children), func(i int) bool {
+		return firstSeg(n.children[i].path) >= seg
+	})
+	if idx < len(n.children) && firstSeg(n.children[idx].path) == seg {
+		return idx, n.children[idx]
+	}
+	return idx, nil
+}
+
+func isStaticSeg(seg string) bool {
+	return seg[0] != ':' && seg[0] != '*'
+}
+
+// firstSeg 压缩节点的第一个 seg
+func firstSeg(p string) string {
+	if idx := strings.IndexByte(p, '/'); idx >= 0 {
+		return p[:idx]
+	}
+	return p
+}
+
+// checkRoute 校验路由是否和已注册的路由冲突, 不会修改路由树
+func (r *router) checkRoute(method string, path string, strict bool, predicates []Predicate) error {
+	// validate the method must be one of the http.Method
+	if !r.supportedMethod[method] {
+		return newRouteError(ErrMethodNotSupported, method, path, "")
+	}
+	segs := splitPath(path)
+	for idx, seg := range segs {
+		// *name 会捕获剩余的整个路径, 只能作为最后一段
+		if strings.HasPrefix(seg, "*") && seg != "*" && idx != len(segs)-1 {
+			return newRouteError(ErrInvalidCatchAll, method, path, "")
+		}
+		if strings.HasPrefix(seg, ":") {
+			if _, _, err := parseParam(seg); err != nil {
+				return newRouteError(err, method, path, "")
 			}
-			// 排序
-			before := cur.children[:insertIndex]
-			after := cur.children[insertIndex:]
-			children := make([]*node, 0, len(cur.children)+1)
-			children = append(children, before...)
-			children = append(children, next)
-			children = append(children, after...)
-			cur.children = children
-			cur = next
-			continue
 		}
+	}
+	cur, ok := r.tree[method]
+	if !ok {
+		return nil
+	}
+	for idx := 0; idx < len(segs); idx++ {
+		seg := segs[idx]
+		var next *node
+		switch {
+		case strings.HasPrefix(seg, "*"):
+			// 不允许同时存在 * 和 :
+			if cur.paramChild != nil {
+				return newRouteError(ErrWildcardConflict, method, path, cur.paramChild.firstPattern())
+			}
+			// * 和 *name, 或者名字不同的 *name 不能共存
+			if cur.starChild != nil && cur.starChild.path != seg {
+				return newRouteError(ErrWildcardConflict, method, path, cur.starChild.firstPattern())
+			}
+			next = cur.starChild
+		case strings.HasPrefix(seg, ":"):
+			name, regExpr, _ := parseParam(seg)
+			if regExpr != nil {
+				for _, child := range cur.regChildren {
+					if child.regExpr.String() != regExpr.String() {
+						continue
+					}
+					if strict && child.path != name {
+						return newRouteError(ErrParamConflict, method, path, child.firstPattern())
+					}
+					if child.path == name {
+						next = child
+					}
+				}
+				break
+			}
+			// 不允许同时存在 * 和 :
+			if cur.starChild != nil {
+				return newRouteError(ErrWildcardConflict, method, path, cur.starChild.firstPattern())
+			}
+			if strict && cur.paramChild != nil && cur.paramChild.path != name {
+				return newRouteError(ErrParamConflict, method, path, cur.paramChild.firstPattern())
+			}
+			next = cur.paramChild
+		default:
+			_, child := cur.childIndex(seg)
+			if child == nil {
+				break
+			}
+			// 压缩节点需要完整匹配, 否则是新的分支
+			childSegs := strings.Split(child.path, "/")
+			if idx+len(childSegs) > len(segs) {
+				break
+			}
+			matched := true
+			for i, childSeg := range childSegs {
+				if segs[idx+i] != childSeg {
+					matched = false
+					break
+				}
+			}
+			if matched {
+				next = child
+				idx += len(childSegs) - 1
+			}
+		}
+		// 新的分支, 不会再有冲突
+		if next == nil {
+			return nil
+		}
+		cur = next
+	}
+	if strict && cur.hasChain(predicates) {
+		return newRouteError(ErrDuplicateRoute, method, path, cur.matchedPath)
+	}
+	return nil
+}
 
-		// 需要添加到队尾
-		// 创建
-		next := &node{
-			path:     seg,
-			children: make([]*node, 0),
+// firstPattern 返回子树中第一个注册的路由, 用于错误提示
+func (n *node) firstPattern() string {
+	pattern := ""
+	n.walk(func(n *node) {
+		if pattern == "" && n.isLeaf() {
+			pattern = n.matchedPath
 		}
+	})
+	return pattern
+}
 
-		// 是叶子节点,绑定调用链
-		if total == idx+1 {
-			next.matchedPath = path
-			next.handlerChains = handlerChain
+// SetName 给路由命名, 名字在整个 router 中唯一
+func (r *router) SetName(n *node, name string) {
+	if exists, ok := r.names[name]; ok && exists != n {
+		panic(fmt.Sprintf("route name: %s already exists", name))
+	}
+	if n.name != "" {
+		delete(r.names, n.name)
+	}
+	n.name = name
+	r.names[name] = n
+}
+
+// URLFor 根据路由名字和路径参数生成 URL
+// params 为 key, value 交替出现的路径参数, 例如 URLFor("user-fav", "id", 1, "fid", 2)
+func (r *router) URLFor(name string, params ...any) (string, error) {
+	n, ok := r.names[name]
+	if !ok {
+		return "", fmt.Errorf("%w: %s", ErrRouteNameNotFound, name)
+	}
+	if len(params)%2 != 0 {
+		return "", fmt.Errorf("%w: params of route %s should be key value pairs", ErrInvalidURLParams, name)
+	}
+	values := make(map[string]string, len(params)/2)
+	for i := 0; i < len(params); i += 2 {
+		key, ok := params[i].(string)
+		if !ok {
+			return "", fmt.Errorf("%w: key %v of route %s should be string", ErrInvalidURLParams, params[i], name)
 		}
-		cur.children = append(cur.children, next)
-		cur = next
+		values[key] = fmt.Sprint(params[i+1])
+	}
+
+	segs := splitPath(n.matchedPath)
+	var sb strings.Builder
+	for _, seg := range segs {
+		sb.WriteByte('/')
+		switch {
+		case strings.HasPrefix(seg, "*"):
+			key := seg
+			if seg != "*" {
+				key = seg[1:]
+			}
+			val, ok := values[key]
+			if !ok {
+				return "", fmt.Errorf("%w: %s of route %s", ErrMissingURLParam, key, name)
+			}
+			sb.WriteString(escapePath(val))
+		case strings.HasPrefix(seg, ":"):
+			key, regExpr, _ := parseParam(seg)
+			val, ok := values[key]
+			if !ok {
+				return "", fmt.Errorf("%w: %s of route %s", ErrMissingURLParam, key, name)
+			}
+			if regExpr != nil && !regExpr.MatchString(val) {
+				return "", fmt.Errorf("%w: %s=%s of route %s should match %s", ErrInvalidURLParams, key, val, name, regExpr.String())
+			}
+			sb.WriteString(url.PathEscape(val))
+		default:
+			sb.WriteString(seg)
+		}
+	}
+	if sb.Len() == 0 || strings.HasSuffix(n.matchedPath, "/") {
+		sb.WriteByte('/')
 	}
+	return sb.String(), nil
 }
 
-// 这种路径参数只支持,同名,且唯一一个
-func (r *router) FindRoute(method string, path string) (n *MatchNode, found bool) {
+// escapePath 逐段转义, 保留 /
+func escapePath(p string) string {
+	segs := strings.Split(strings.TrimPrefix(p, "/"), "/")
+	for idx, seg := range segs {
+		segs[idx] = url.PathEscape(seg)
+	}
+	return strings.Join(segs, "/")
+}
+
+// FindRoute 查找路由, 匹配优先级: 静态路由 > 正则路径参数 > 路径参数 > *
+// 某个分支匹配失败时会回溯, 尝试优先级更低的兄弟节点
+// 直接在 path 上按下标匹配, 不切割 path, 静态路由匹配时没有内存分配
+// 不检查路由的匹配条件, 处理请求时使用 findRoute
+func (r *router) FindRoute(method string, path string) (n MatchNode, found bool) {
+	return r.findRoute(method, path, nil)
+}
+
+// findRoute 和 FindRoute 相同, 只有带匹配条件的路由并且条件都不满足 req 时, 继续尝试其他路由
+// req 为 nil 时不检查匹配条件
+func (r *router) findRoute(method string, path string, req *http.Request) (n MatchNode, found bool) {
 
 	curNode, ok := r.tree[method]
 	if !ok {
-		return nil, false
+		return MatchNode{}, false
 	}
 
-	var pathParams = make(url.Values)
-	var starNode *node
-	// 根路径
-	if curNode.path == path {
-		if len(curNode.handlerChains) > 0 {
-			return &MatchNode{
-				node:       curNode,
-				pathParams: pathParams,
-			}, true
+	leaf, params, found := r.find(curNode, path, 0, nil, req)
+	if !found {
+		return MatchNode{}, false
+	}
+	// 只有存在路径参数时才分配
+	var pathParams url.Values
+	if len(params) > 0 {
+		pathParams = make(url.Values, len(params))
+		for _, param := range params {
+			pathParams[param.key] = append(pathParams[param.key], param.value)
 		}
-		return nil, false
 	}
+	return MatchNode{
+		node:       leaf,
+		pathParams: pathParams,
+	}, true
+}
 
-	segs := strings.Split(path, "/")
-	total := len(segs)
-	for idx, seg := range segs {
-		if seg == "" {
-			continue
+// AllowedMethods 返回 req 的 path 在所有 method 下已注册的 method, 按字母序排列
+// 匹配条件都不满足的路由不算在内
+func (r *router) AllowedMethods(req *http.Request) []string {
+	allowed := make([]string, 0, len(r.tree))
+	for method := range r.tree {
+		if _, found := r.findRoute(method, req.URL.Path, req); found {
+			allowed = append(allowed, method)
 		}
+	}
+	sort.Strings(allowed)
+	return allowed
+}
 
-		// TODO: 二分查找...因为是排序了的...
-		// 查找所有的children
-		shouldMatchOther := true
-		for _, child := range curNode.children {
-			if child.path == seg {
-				if len(child.handlerChains) > 0 &&
-					idx+1 == total {
-					return &MatchNode{
-						node:       child,
-						pathParams: pathParams,
-					}, true
-				}
-				shouldMatchOther = false
-				curNode = child
-			}
+// find 在 n 的子树中查找 path[start:] 对应的叶子节点
+func (r *router) find(n *node, path string, start int, params []pathParam, req *http.Request) (*node, []pathParam, bool) {
+	seg, next := nextSeg(path, start)
+	if seg == "" {
+		if n.accepts(req) {
+			return n, params, true
+		}
+		// *name 也可以匹配空的剩余路径, 例如 /static/*filepath 匹配 /static/, filepath 为空
+		if n.starChild != nil && n.starChild.isCatchAll() && n.starChild.accepts(req) {
+			return n.starChild, append(params, pathParam{key: n.starChild.path[1:]}), true
 		}
+		return nil, params, false
+	}
 
-		// 路径参数匹配优先级 > *匹配
-		if curNode.paramChild != nil && shouldMatchOther {
-			// 记录pathParams,传递给最终的叶子节点.
-			if !pathParams.Has(curNode.paramChild.path) {
-				pathParams[curNode.paramChild.path] = make([]string, 0)
+	// 静态路由
+	if r.ignoreCase {
+		for _, child := range n.children {
+			if end, ok := r.matchStatic(child.path, path, start); ok {
+				if leaf, ps, ok := r.find(child, path, end, params, req); ok {
+					return leaf, ps, true
+				}
 			}
-			pathParams.Add(curNode.paramChild.path, seg)
-			if len(curNode.paramChild.handlerChains) > 0 &&
-				idx+1 == total {
-				return &MatchNode{
-					node:       curNode.paramChild,
-					pathParams: pathParams,
-				}, true
+		}
+	} else if _, child := n.childIndex(seg); child != nil {
+		if end, ok := r.matchStatic(child.path, path, start); ok {
+			if leaf, ps, ok := r.find(child, path, end, params, req); ok {
+				return leaf, ps, true
 			}
-			curNode = curNode.paramChild
+		}
+	}
+
+	// 正则路径参数, 不匹配的 seg 交给后面的兄弟节点
+	for _, child := range n.regChildren {
+		if !child.regExpr.MatchString(seg) {
 			continue
 		}
+		if leaf, ps, ok := r.find(child, path, next, append(params, pathParam{key: child.path, value: seg}), req); ok {
+			return leaf, ps, true
+		}
+	}
 
-		// *匹配
-		if curNode.starChild != nil && shouldMatchOther {
-			if len(curNode.starChild.handlerChains) > 0 &&
-				idx+1 == total {
-				return &MatchNode{
-					node:       curNode.starChild,
-					pathParams: pathParams,
-				}, true
-			}
-			curNode = curNode.starChild
+	// 路径参数
+	if n.paramChild != nil {
+		if leaf, ps, ok := r.find(n.paramChild, path, next, append(params, pathParam{key: n.paramChild.path, value: seg}), req); ok {
+			return leaf, ps, true
+		}
+	}
 
-			// 用来最终溯回,兜底
-			starNode = curNode
-			continue
+	// *name 匹配剩余的整个路径
+	if n.starChild != nil && n.starChild.isCatchAll() {
+		if n.starChild.accepts(req) {
+			return n.starChild, append(params, pathParam{key: n.starChild.path[1:], value: strings.TrimLeft(path[start:], "/")}), true
 		}
+		return nil, params, false
 	}
 
-	// 最终溯回,兜底
-	if starNode != nil {
-		return &MatchNode{
-			node:       starNode,
-			pathParams: pathParams,
-		}, true
+	// *匹配, 在中间时只匹配一段
+	if n.starChild != nil {
+		if leaf, ps, ok := r.find(n.starChild, path, next, params, req); ok {
+			return leaf, ps, true
+		}
+		// 兜底, * 匹配剩余的所有 seg
+		if n.starChild.accepts(req) {
+			return n.starChild, params, true
+		}
 	}
-	return &MatchNode{
-		node:       nil,
-		pathParams: pathParams,
-	}, false
+	return nil, params, false
+}
+
+// matchStatic 用压缩节点的 nodePath 逐段匹配 path[start:], 返回匹配之后的下标
+func (r *router) matchStatic(nodePath string, path string, start int) (end int, ok bool) {
+	end = start
+	for nodePath != "" {
+		var want string
+		if idx := strings.IndexByte(nodePath, '/'); idx >= 0 {
+			want, nodePath = nodePath[:idx], nodePath[idx+1:]
+		} else {
+			want, nodePath = nodePath, ""
+		}
+		var seg string
+		seg, end = nextSeg(path, end)
+		if seg != want && !(r.ignoreCase && strings.EqualFold(seg, want)) {
+			return start, false
+		}
+	}
+	return end, true
+}
+
+// nextSeg 返回 path[start:] 中的第一个非空 seg, 以及这个 seg 之后的下标
+func nextSeg(path string, start int) (seg string, next int) {
+	for start < len(path) && path[start] == '/' {
+		start++
+	}
+	end := start
+	for end < len(path) && path[end] != '/' {
+		end++
+	}
+	return path[start:end], end
+}
+
+// isCatchAll 是否是 *name 节点
+func (n *node) isCatchAll() bool {
+	return len(n.path) > 1 && n.path[0] == '*'
+}
+
+// splitPath 切割 path, 并去除空的 seg
+func splitPath(path string) []string {
+	segs := strings.Split(path, "/")
+	res := make([]string, 0, len(segs))
+	for _, seg := range segs {
+		if seg != "" {
+			res = append(res, seg)
+		}
+	}
+	return res
+}
+
+// paramTypes 路径参数的类型简写, 例如 :id<int>
+var paramTypes = map[string]string{
+	"int":   `-?[0-9]+`,
+	"uint":  `[0-9]+`,
+	"float": `-?[0-9]+(\.[0-9]+)?`,
+	"bool":  `true|false`,
+	"alpha": `[a-zA-Z]+`,
+	"alnum": `[a-zA-Z0-9]+`,
+	"uuid":  `[0-9a-fA-F]{8}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{12}`,
+}
+
+// parseParam 解析路径参数, 支持 :id, :id(\d+) 和 :id<int> 三种写法
+// 正则不合法或者类型不存在时返回 ErrInvalidParam, checkRoute 会先校验, 之后的调用可以忽略错误
+func parseParam(seg string) (name string, regExpr *regexp.Regexp, err error) {
+	seg = strings.TrimPrefix(seg, ":")
+	var expr string
+	if idx := strings.IndexByte(seg, '('); idx > 0 && strings.HasSuffix(seg, ")") {
+		name, expr = seg[:idx], seg[idx+1:len(seg)-1]
+	} else if idx := strings.IndexByte(seg, '<'); idx > 0 && strings.HasSuffix(seg, ">") {
+		typ := seg[idx+1 : len(seg)-1]
+		var ok bool
+		if expr, ok = paramTypes[typ]; !ok {
+			return "", nil, fmt.Errorf("%w: unknown type %s", ErrInvalidParam, typ)
+		}
+		name = seg[:idx]
+	} else {
+		return seg, nil, nil
+	}
+	// 需要完整匹配整个 seg
+	regExpr, err = regexp.Compile("^(?:" + expr + ")$")
+	if err != nil {
+		return "", nil, fmt.Errorf("%w: %v", ErrInvalidParam, err)
+	}
+	return name, regExpr, nil
+}
+
+type pathParam struct {
+	key   string
+	value string
 }
 
 // 定义api接口.
@@ -273,13 +662,120 @@ func (r *router) FindRoute(method string, path string) (n *MatchNode, found bool
 type node struct {
 	path        string
 	matchedPath string
-	children    []*node
+	// 路由名字, 用来反向生成 URL
+	name     string
+	children []*node
 	// 通配符
 	starChild *node
 	// 路径参数
 	paramChild *node
+	// 带正则约束的路径参数, 按注册顺序匹配
+	regChildren []*node
+	regExpr     *regexp.Regexp
 	// 责任链
 	handlerChains []HandleFunc
+	// 带匹配条件的责任链, 按注册顺序匹配, 都不满足时使用 handlerChains
+	variants []*variant
+}
+
+// clone 深拷贝子树
+func (n *node) clone() *node {
+	c := *n
+	c.children = make([]*node, 0, len(n.children))
+	for _, child := range n.children {
+		c.children = append(c.children, child.clone())
+	}
+	c.regChildren = nil
+	for _, child := range n.regChildren {
+		c.regChildren = append(c.regChildren, child.clone())
+	}
+	if n.paramChild != nil {
+		c.paramChild = n.paramChild.clone()
+	}
+	if n.starChild != nil {
+		c.starChild = n.starChild.clone()
+	}
+	c.variants = append([]*variant(nil), n.variants...)
+	return &c
+}
+
+// prune 删除子树中没有路由的节点, 返回 n 本身是否也可以删除
+func (n *node) prune() bool {
+	children := n.children[:0]
+	for _, child := range n.children {
+		if !child.prune() {
+			children = append(children, child)
+		}
+	}
+	n.children = children
+	regChildren := n.regChildren[:0]
+	for _, child := range n.regChildren {
+		if !child.prune() {
+			regChildren = append(regChildren, child)
+		}
+	}
+	n.regChildren = regChildren
+	if n.paramChild != nil && n.paramChild.prune() {
+		n.paramChild = nil
+	}
+	if n.starChild != nil && n.starChild.prune() {
+		n.starChild = nil
+	}
+	return !n.isLeaf() && len(n.children) == 0 && len(n.regChildren) == 0 &&
+		n.paramChild == nil && n.starChild == nil
+}
+
+// isLeaf 是否注册了路由
+func (n *node) isLeaf() bool {
+	return len(n.handlerChains) > 0 || len(n.variants) > 0
+}
+
+// hasChain 是否已经注册了匹配条件相同的路由
+func (n *node) hasChain(predicates []Predicate) bool {
+	if len(predicates) == 0 {
+		return len(n.handlerChains) > 0
+	}
+	key := predicateKey(predicates)
+	for _, v := range n.variants {
+		if v.key == key {
+			return true
+		}
+	}
+	return false
+}
+
+// setVariant 添加带匹配条件的责任链, 条件相同时覆盖之前注册的
+func (n *node) setVariant(v *variant) {
+	for i, exists := range n.variants {
+		if exists.key == v.key {
+			n.variants[i] = v
+			return
+		}
+	}
+	n.variants = append(n.variants, v)
+}
+
+// accepts 是否有可以处理 req 的责任链, req 为 nil 时只判断是否注册了路由
+func (n *node) accepts(req *http.Request) bool {
+	if req == nil || len(n.handlerChains) > 0 {
+		return n.isLeaf()
+	}
+	for _, v := range n.variants {
+		if v.match(req) {
+			return true
+		}
+	}
+	return false
+}
+
+// handlerChainsFor 返回第一个满足匹配条件的责任链, 都不满足时返回没有匹配条件的责任链
+func (n *node) handlerChainsFor(req *http.Request) []HandleFunc {
+	for _, v := range n.variants {
+		if v.match(req) {
+			return v.handlerChains
+		}
+	}
+	return n.handlerChains
 }
 
 type MatchNode struct {
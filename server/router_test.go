@@ -4,7 +4,9 @@ import (
 	"errors"
 	"fmt"
 	"net/http"
+	"net/url"
 	"reflect"
+	"strings"
 	"testing"
 
 	"github.com/stretchr/testify/require"
@@ -61,17 +63,12 @@ func TestRouter_AddRoute(t *testing.T) {
 							matchedPath: "/home",
 						},
 						{
-							path: "user",
-							children: []*node{
-								{
-									path:     "home",
-									children: make([]*node, 0),
-									handlerChains: []HandleFunc{
-										mockHandler,
-									},
-									matchedPath: "/user/home",
-								},
+							path:     "user/home",
+							children: make([]*node, 0),
+							handlerChains: []HandleFunc{
+								mockHandler,
 							},
+							matchedPath: "/user/home",
 						},
 					},
 				},
@@ -142,17 +139,13 @@ func TestRouter_AddRoute(t *testing.T) {
 					path: "/",
 					children: []*node{
 						{
-							path: "users",
-							children: []*node{
-								{
-									path:     "id",
-									children: make([]*node, 0),
-									handlerChains: []HandleFunc{
-										mockHandler,
-									},
-									matchedPath: "/users/id",
-								},
+							// 中间节点没有 handler, 压缩到同一个节点
+							path:     "users/id",
+							children: make([]*node, 0),
+							handlerChains: []HandleFunc{
+								mockHandler,
 							},
+							matchedPath: "/users/id",
 						},
 					},
 				},
@@ -160,17 +153,13 @@ func TestRouter_AddRoute(t *testing.T) {
 					path: "/",
 					children: []*node{
 						{
-							path: "users",
-							children: []*node{
-								{
-									path:     "id",
-									children: make([]*node, 0),
-									handlerChains: []HandleFunc{
-										mockHandler,
-									},
-									matchedPath: "/users/id",
-								},
+							// 中间节点没有 handler, 压缩到同一个节点
+							path:     "users/id",
+							children: make([]*node, 0),
+							handlerChains: []HandleFunc{
+								mockHandler,
 							},
+							matchedPath: "/users/id",
 						},
 					},
 				},
@@ -178,17 +167,13 @@ func TestRouter_AddRoute(t *testing.T) {
 					path: "/",
 					children: []*node{
 						{
-							path: "users",
-							children: []*node{
-								{
-									path:     "id",
-									children: make([]*node, 0),
-									handlerChains: []HandleFunc{
-										mockHandler,
-									},
-									matchedPath: "/users/id",
-								},
+							// 中间节点没有 handler, 压缩到同一个节点
+							path:     "users/id",
+							children: make([]*node, 0),
+							handlerChains: []HandleFunc{
+								mockHandler,
 							},
+							matchedPath: "/users/id",
 						},
 					},
 				},
@@ -443,7 +428,7 @@ func TestRouter_AddRoute(t *testing.T) {
 	}
 
 	// mock 方案
-	for idx, tc := range testCases {
+	for _, tc := range testCases {
 		r := newRouter()
 		t.Log(tc.name)
 		if tc.shouldPanic {
@@ -457,9 +442,6 @@ func TestRouter_AddRoute(t *testing.T) {
 		for _, route := range tc.routes {
 			r.AddRoute(route.method, route.path, route.middlewares, route.handler)
 		}
-		if idx == 0 {
-			continue
-		}
 		// 断言
 		require.NoError(t, treeEqual(tc.wantTree, r.tree))
 	}
@@ -895,6 +877,15 @@ func nodeEqual(src *node, dst *node) error {
 		return err
 	}
 
+	if len(src.regChildren) != len(dst.regChildren) {
+		return fmt.Errorf("src regChildren length: [%+v] not equal to dst regChildren length: [%+v]", src, dst)
+	}
+	for i := 0; i < len(src.regChildren); i++ {
+		if err := nodeEqual(src.regChildren[i], dst.regChildren[i]); err != nil {
+			return err
+		}
+	}
+
 	for i := 0; i < len(src.children); i++ {
 		err := nodeEqual(src.children[i], dst.children[i])
 		if err != nil {
@@ -909,3 +900,686 @@ func nodeEqual(src *node, dst *node) error {
 	}
 	return nil
 }
+
+// go test -v server/*.go -run TestRouter_FindRouteWithRegexp
+func TestRouter_FindRouteWithRegexp(t *testing.T) {
+
+	var mockHandler HandleFunc = func(ctx *Context) {}
+	routes := []string{
+		`/order/:id(\d+)`,
+		`/order/export`,
+		`/order/:id(\d+)/detail`,
+		`/file/:name([a-z]+\.png)`,
+		`/file/*`,
+		`/user/:id<int>`,
+		`/user/:name`,
+		`/token/:uuid<uuid>`,
+	}
+	r := newRouter()
+	for _, route := range routes {
+		r.AddRoute(http.MethodGet, route, nil, mockHandler)
+	}
+
+	testCases := []struct {
+		name            string
+		path            string
+		wantFound       bool
+		wantMatchedPath string
+		wantParams      map[string]string
+	}{
+		{
+			name:            "regexp param matched",
+			path:            "/order/123",
+			wantFound:       true,
+			wantMatchedPath: `/order/:id(\d+)`,
+			wantParams:      map[string]string{"id": "123"},
+		},
+		{
+			name:            "static route takes priority",
+			path:            "/order/export",
+			wantFound:       true,
+			wantMatchedPath: `/order/export`,
+		},
+		{
+			name:      "regexp param not matched",
+			path:      "/order/abc",
+			wantFound: false,
+		},
+		{
+			name:            "regexp param with children",
+			path:            "/order/1/detail",
+			wantFound:       true,
+			wantMatchedPath: `/order/:id(\d+)/detail`,
+			wantParams:      map[string]string{"id": "1"},
+		},
+		{
+			name:            "regexp should match the whole seg",
+			path:            "/file/avatar.png",
+			wantFound:       true,
+			wantMatchedPath: `/file/:name([a-z]+\.png)`,
+			wantParams:      map[string]string{"name": "avatar.png"},
+		},
+		{
+			name:            "fall through to star sibling",
+			path:            "/file/avatar.png.bak",
+			wantFound:       true,
+			wantMatchedPath: `/file/*`,
+		},
+		{
+			name:            "typed param",
+			path:            "/user/-12",
+			wantFound:       true,
+			wantMatchedPath: `/user/:id<int>`,
+			wantParams:      map[string]string{"id": "-12"},
+		},
+		{
+			name:            "fall through to param sibling",
+			path:            "/user/tom",
+			wantFound:       true,
+			wantMatchedPath: `/user/:name`,
+			wantParams:      map[string]string{"name": "tom"},
+		},
+		{
+			name:            "uuid param",
+			path:            "/token/0190163d-8694-739b-aea5-966c26f8ad91",
+			wantFound:       true,
+			wantMatchedPath: `/token/:uuid<uuid>`,
+			wantParams:      map[string]string{"uuid": "0190163d-8694-739b-aea5-966c26f8ad91"},
+		},
+		{
+			name:      "uuid param not matched",
+			path:      "/token/123",
+			wantFound: false,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			matchInfo, found := r.FindRoute(http.MethodGet, tc.path)
+			require.Equal(t, tc.wantFound, found)
+			if !found {
+				return
+			}
+			require.Equal(t, tc.wantMatchedPath, matchInfo.node.matchedPath)
+			for key, val := range tc.wantParams {
+				require.Equal(t, val, matchInfo.pathParams.Get(key))
+			}
+		})
+	}
+
+	require.Panics(t, func() {
+		r.AddRoute(http.MethodGet, "/user/:id<unknown>", nil, mockHandler)
+	})
+}
+
+// go test -v server/*.go -run TestRouter_AddRouteE
+func TestRouter_AddRouteE(t *testing.T) {
+
+	var mockHandler HandleFunc = func(ctx *Context) {}
+	testCases := []struct {
+		name         string
+		routes       []string
+		method       string
+		path         string
+		wantErr      error
+		wantExisting string
+	}{
+		{
+			name:    "method not supported",
+			method:  "UNKNOWN",
+			path:    "/user",
+			wantErr: ErrMethodNotSupported,
+		},
+		{
+			name:         "duplicate route",
+			routes:       []string{"/user/:id"},
+			method:       http.MethodGet,
+			path:         "/user/:id",
+			wantErr:      ErrDuplicateRoute,
+			wantExisting: "/user/:id",
+		},
+		{
+			name:         "duplicate root",
+			routes:       []string{"/"},
+			method:       http.MethodGet,
+			path:         "/",
+			wantErr:      ErrDuplicateRoute,
+			wantExisting: "/",
+		},
+		{
+			name:         "param name mismatch",
+			routes:       []string{"/a/:id"},
+			method:       http.MethodGet,
+			path:         "/a/:uid",
+			wantErr:      ErrParamConflict,
+			wantExisting: "/a/:id",
+		},
+		{
+			name:         "regexp param name mismatch",
+			routes:       []string{`/a/:id(\d+)`},
+			method:       http.MethodGet,
+			path:         `/a/:uid(\d+)`,
+			wantErr:      ErrParamConflict,
+			wantExisting: `/a/:id(\d+)`,
+		},
+		{
+			name:         "star after param",
+			routes:       []string{"/a/:id/b"},
+			method:       http.MethodGet,
+			path:         "/a/*",
+			wantErr:      ErrWildcardConflict,
+			wantExisting: "/a/:id/b",
+		},
+		{
+			name:         "param after star",
+			routes:       []string{"/a/*"},
+			method:       http.MethodGet,
+			path:         "/a/:id",
+			wantErr:      ErrWildcardConflict,
+			wantExisting: "/a/*",
+		},
+		{
+			name:    "invalid param regexp",
+			method:  http.MethodGet,
+			path:    "/a/:id([)",
+			wantErr: ErrInvalidParam,
+		},
+		{
+			name:    "unknown param type",
+			routes:  []string{"/b/:id<int>"},
+			method:  http.MethodGet,
+			path:    "/b/:id<nope>",
+			wantErr: ErrInvalidParam,
+		},
+		{
+			name:   "same route with another method",
+			routes: []string{"/user/:id"},
+			method: http.MethodPost,
+			path:   "/user/:id",
+		},
+		{
+			name:   "regexp param with star",
+			routes: []string{"/a/*"},
+			method: http.MethodGet,
+			path:   `/a/:id(\d+)`,
+		},
+		{
+			name:   "extend existing route",
+			routes: []string{"/a/:id"},
+			method: http.MethodGet,
+			path:   "/a/:id/b",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			r := newRouter()
+			for _, route := range tc.routes {
+				r.AddRoute(http.MethodGet, route, nil, mockHandler)
+			}
+			before := r.Routes()
+			_, err := r.AddRouteE(tc.method, tc.path, nil, mockHandler)
+			require.True(t, errors.Is(err, tc.wantErr), err)
+			if tc.wantErr == nil {
+				return
+			}
+			var routeErr *RouteError
+			require.True(t, errors.As(err, &routeErr))
+			require.Equal(t, tc.method, routeErr.Method)
+			require.Equal(t, tc.path, routeErr.Path)
+			require.Equal(t, tc.wantExisting, routeErr.Existing)
+			// 冲突时不应该修改路由树
+			require.Equal(t, before, r.Routes())
+		})
+	}
+}
+
+// go test -v server/*.go -run TestHTTPServer_RouteValidation
+func TestHTTPServer_RouteValidation(t *testing.T) {
+
+	var mockHandler HandleFunc = func(ctx *Context) {}
+
+	// 默认模式下, 重复注册会覆盖
+	serv := New(":8081")
+	serv.Get("/user", mockHandler)
+	require.NotPanics(t, func() {
+		serv.Get("/user", mockHandler)
+	})
+	require.Panics(t, func() {
+		serv.AddRoute("UNKNOWN", "/user", mockHandler)
+	})
+
+	serv = New(":8081", WithRouteValidation(true))
+	serv.Get("/user", mockHandler)
+	require.Panics(t, func() {
+		serv.Get("/user", mockHandler)
+	})
+
+	_, err := serv.Group("/api").AddRouteE(http.MethodGet, "/user", mockHandler)
+	require.NoError(t, err)
+	_, err = serv.Group("/api").AddRouteE(http.MethodGet, "/user", mockHandler)
+	require.ErrorIs(t, err, ErrDuplicateRoute)
+
+	// 路径参数不合法时返回错误, 不会 panic
+	_, err = serv.AddRouteE(http.MethodGet, "/a/:id([)", mockHandler)
+	require.ErrorIs(t, err, ErrInvalidParam)
+	_, err = serv.AddRouteE(http.MethodGet, "/b/:id<nope>", mockHandler)
+	require.EqualError(t, err, "route [GET] /b/:id<nope>: invalid path param: unknown type nope")
+}
+
+// go test -v server/*.go -run TestRouter_FindRouteWithCatchAll
+func TestRouter_FindRouteWithCatchAll(t *testing.T) {
+
+	var mockHandler HandleFunc = func(ctx *Context) {}
+	routes := []string{
+		"/static/*filepath",
+		"/static/favicon.ico",
+		"/a/*/b",
+		"/c/*",
+		"/c/*/d",
+	}
+	r := newRouter()
+	for _, route := range routes {
+		r.AddRoute(http.MethodGet, route, nil, mockHandler)
+	}
+
+	testCases := []struct {
+		name            string
+		path            string
+		wantFound       bool
+		wantMatchedPath string
+		wantParams      map[string]string
+	}{
+		{
+			name:            "catch-all with one segment",
+			path:            "/static/main.css",
+			wantFound:       true,
+			wantMatchedPath: "/static/*filepath",
+			wantParams:      map[string]string{"filepath": "main.css"},
+		},
+		{
+			name:            "catch-all with multi segments",
+			path:            "/static/css/theme/main.css",
+			wantFound:       true,
+			wantMatchedPath: "/static/*filepath",
+			wantParams:      map[string]string{"filepath": "css/theme/main.css"},
+		},
+		{
+			name:            "catch-all keeps trailing slash",
+			path:            "/static/css/",
+			wantFound:       true,
+			wantMatchedPath: "/static/*filepath",
+			wantParams:      map[string]string{"filepath": "css/"},
+		},
+		{
+			name:            "static route takes priority",
+			path:            "/static/favicon.ico",
+			wantFound:       true,
+			wantMatchedPath: "/static/favicon.ico",
+		},
+		{
+			name:            "catch-all matches empty remainder",
+			path:            "/static/",
+			wantFound:       true,
+			wantMatchedPath: "/static/*filepath",
+			wantParams:      map[string]string{"filepath": ""},
+		},
+		{
+			name:            "catch-all matches empty remainder without slash",
+			path:            "/static",
+			wantFound:       true,
+			wantMatchedPath: "/static/*filepath",
+			wantParams:      map[string]string{"filepath": ""},
+		},
+		{
+			name:      "star without name needs at least one segment",
+			path:      "/c/",
+			wantFound: false,
+		},
+		{
+			name:            "mid-path star matches one segment",
+			path:            "/a/x/b",
+			wantFound:       true,
+			wantMatchedPath: "/a/*/b",
+		},
+		{
+			name:      "mid-path star should not match multi segments",
+			path:      "/a/x/y/b",
+			wantFound: false,
+		},
+		{
+			name:            "mid-path star with children",
+			path:            "/c/x/d",
+			wantFound:       true,
+			wantMatchedPath: "/c/*/d",
+		},
+		{
+			name:            "trailing star as fallback",
+			path:            "/c/x/y/d",
+			wantFound:       true,
+			wantMatchedPath: "/c/*",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			matchInfo, found := r.FindRoute(http.MethodGet, tc.path)
+			require.Equal(t, tc.wantFound, found)
+			if !found {
+				return
+			}
+			require.Equal(t, tc.wantMatchedPath, matchInfo.node.matchedPath)
+			for key, val := range tc.wantParams {
+				// 空字符串也需要设置参数
+				require.Equal(t, []string{val}, matchInfo.pathParams[key])
+			}
+		})
+	}
+
+	// 根路径的 *name 可以匹配 /
+	root := newRouter()
+	root.AddRoute(http.MethodGet, "/*filepath", nil, mockHandler)
+	matchInfo, found := root.FindRoute(http.MethodGet, "/")
+	require.True(t, found)
+	require.Equal(t, "/*filepath", matchInfo.node.matchedPath)
+	require.Equal(t, []string{""}, matchInfo.pathParams["filepath"])
+
+	_, err := r.AddRouteE(http.MethodGet, "/files/*filepath/info", nil, mockHandler)
+	require.ErrorIs(t, err, ErrInvalidCatchAll)
+	_, err = r.AddRouteE(http.MethodGet, "/static/*file", nil, mockHandler)
+	require.ErrorIs(t, err, ErrWildcardConflict)
+	_, err = r.AddRouteE(http.MethodGet, "/c/*filepath", nil, mockHandler)
+	require.ErrorIs(t, err, ErrWildcardConflict)
+}
+
+// benchRoutes 模拟一个有上千条路由的服务
+func benchRoutes() []string {
+	routes := make([]string, 0, 1000)
+	for i := 0; i < 200; i++ {
+		resource := fmt.Sprintf("/api/v1/resource%d", i)
+		routes = append(routes,
+			resource,
+			resource+"/:id",
+			resource+"/:id/items",
+			resource+"/:id/items/:itemId",
+			fmt.Sprintf("/api/v2/resource%d/list/all", i),
+		)
+	}
+	return routes
+}
+
+func newBenchRouter() *router {
+	var mockHandler HandleFunc = func(ctx *Context) {}
+	r := newRouter()
+	for _, route := range benchRoutes() {
+		r.AddRoute(http.MethodGet, route, nil, mockHandler)
+	}
+	return r
+}
+
+// linearRouter 压缩节点之前的实现, 只在 benchmark 中用来对比
+// 每个 seg 一个节点, 线性扫描静态子节点, 查找时切割 path 并且总是分配 url.Values
+// 只支持 benchmark 用到的静态路由和路径参数
+type linearRouter struct {
+	root *linearNode
+}
+
+type linearNode struct {
+	path        string
+	matchedPath string
+	children    []*linearNode
+	paramChild  *linearNode
+}
+
+func newLinearRouter(routes []string) *linearRouter {
+	r := &linearRouter{root: &linearNode{path: "/"}}
+	for _, route := range routes {
+		cur := r.root
+		for _, seg := range splitPath(route) {
+			if strings.HasPrefix(seg, ":") {
+				if cur.paramChild == nil {
+					cur.paramChild = &linearNode{path: seg[1:]}
+				}
+				cur = cur.paramChild
+				continue
+			}
+			var next *linearNode
+			for _, child := range cur.children {
+				if child.path == seg {
+					next = child
+					break
+				}
+			}
+			if next == nil {
+				next = &linearNode{path: seg}
+				cur.children = append(cur.children, next)
+			}
+			cur = next
+		}
+		cur.matchedPath = route
+	}
+	return r
+}
+
+func (r *linearRouter) FindRoute(path string) (*linearNode, url.Values, bool) {
+	pathParams := make(url.Values)
+	leaf, params, found := r.find(r.root, splitPath(path), nil)
+	if !found {
+		return nil, pathParams, false
+	}
+	for _, param := range params {
+		pathParams.Add(param.key, param.value)
+	}
+	return leaf, pathParams, true
+}
+
+func (r *linearRouter) find(n *linearNode, segs []string, params []pathParam) (*linearNode, []pathParam, bool) {
+	if len(segs) == 0 {
+		return n, params, n.matchedPath != ""
+	}
+	seg := segs[0]
+	for _, child := range n.children {
+		if child.path == seg {
+			if leaf, ps, ok := r.find(child, segs[1:], params); ok {
+				return leaf, ps, true
+			}
+		}
+	}
+	if n.paramChild != nil {
+		return r.find(n.paramChild, segs[1:], append(params, pathParam{key: n.paramChild.path, value: seg}))
+	}
+	return nil, params, false
+}
+
+// benchFinders 压缩节点的 router 和之前线性扫描的实现, 返回 path 是否命中
+func benchFinders() []struct {
+	name string
+	find func(path string) bool
+} {
+	r := newBenchRouter()
+	linear := newLinearRouter(benchRoutes())
+	return []struct {
+		name string
+		find func(path string) bool
+	}{
+		{
+			name: "radix",
+			find: func(path string) bool {
+				_, found := r.FindRoute(http.MethodGet, path)
+				return found
+			},
+		},
+		{
+			name: "linear",
+			find: func(path string) bool {
+				_, _, found := linear.FindRoute(path)
+				return found
+			},
+		},
+	}
+}
+
+// benchPaths 命中每个 benchRoutes 的请求路径
+func benchPaths() []string {
+	paths := make([]string, 0, 1000)
+	for _, route := range benchRoutes() {
+		paths = append(paths, strings.NewReplacer(":id", "123", ":itemId", "456").Replace(route))
+	}
+	return paths
+}
+
+// go test -v server/*.go -run TestRouter_LinearReference
+func TestRouter_LinearReference(t *testing.T) {
+	// 对比的基准和 router 的匹配结果一致
+	r := newBenchRouter()
+	linear := newLinearRouter(benchRoutes())
+	paths := append(benchPaths(), "/api/v3/resource150/list", "/api/v1/resource1/1/items/2/3", "/")
+	for _, p := range paths {
+		matchInfo, found := r.FindRoute(http.MethodGet, p)
+		leaf, params, linearFound := linear.FindRoute(p)
+		require.Equal(t, found, linearFound, p)
+		if found {
+			require.Equal(t, matchInfo.node.matchedPath, leaf.matchedPath)
+			for key, vals := range matchInfo.pathParams {
+				require.Equal(t, vals, params[key])
+			}
+		}
+	}
+}
+
+// go test -bench=BenchmarkRouter_FindRoute -benchmem -run=^$ ./server/
+// radix 为当前的实现, linear 为压缩节点之前的实现
+func BenchmarkRouter_FindRoute_Static(b *testing.B) {
+	benchFindRoute(b, "/api/v2/resource150/list/all", true)
+}
+
+func BenchmarkRouter_FindRoute_Param(b *testing.B) {
+	benchFindRoute(b, "/api/v1/resource150/123/items/456", true)
+}
+
+func BenchmarkRouter_FindRoute_NotFound(b *testing.B) {
+	benchFindRoute(b, "/api/v3/resource150/list", false)
+}
+
+func benchFindRoute(b *testing.B, path string, wantFound bool) {
+	for _, f := range benchFinders() {
+		b.Run(f.name, func(b *testing.B) {
+			b.ReportAllocs()
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				if f.find(path) != wantFound {
+					b.Fatalf("found %s should be %v", path, wantFound)
+				}
+			}
+		})
+	}
+}
+
+func BenchmarkRouter_FindRoute_All(b *testing.B) {
+	paths := benchPaths()
+	for _, f := range benchFinders() {
+		b.Run(f.name, func(b *testing.B) {
+			b.ReportAllocs()
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				for _, p := range paths {
+					if !f.find(p) {
+						b.Fatal("route not found")
+					}
+				}
+			}
+		})
+	}
+}
+
+// go test -v server/*.go -run TestRouter_CompressedNode
+func TestRouter_CompressedNode(t *testing.T) {
+
+	var mockHandler HandleFunc = func(ctx *Context) {}
+	r := newRouter()
+	r.AddRoute(http.MethodGet, "/api/v1/user", nil, mockHandler)
+	leaf := r.AddRoute(http.MethodGet, "/api/v1/order/list", nil, mockHandler)
+	r.AddRoute(http.MethodGet, "/api/v2", nil, mockHandler)
+	r.AddRoute(http.MethodGet, "/api/v1", nil, mockHandler)
+	r.AddRoute(http.MethodGet, "/about", nil, mockHandler)
+
+	wantTree := map[string]*node{
+		http.MethodGet: {
+			path: "/",
+			children: []*node{
+				{
+					path:          "about",
+					handlerChains: []HandleFunc{mockHandler},
+					matchedPath:   "/about",
+				},
+				{
+					path: "api",
+					children: []*node{
+						{
+							path: "v1",
+							children: []*node{
+								{
+									path:          "order/list",
+									handlerChains: []HandleFunc{mockHandler},
+									matchedPath:   "/api/v1/order/list",
+								},
+								{
+									path:          "user",
+									handlerChains: []HandleFunc{mockHandler},
+									matchedPath:   "/api/v1/user",
+								},
+							},
+							handlerChains: []HandleFunc{mockHandler},
+							matchedPath:   "/api/v1",
+						},
+						{
+							path:          "v2",
+							handlerChains: []HandleFunc{mockHandler},
+							matchedPath:   "/api/v2",
+						},
+					},
+				},
+			},
+		},
+	}
+	require.NoError(t, treeEqual(wantTree, r.tree))
+
+	// 分裂之后, 已经注册的叶子节点不变
+	matchInfo, found := r.FindRoute(http.MethodGet, "/api/v1/order/list")
+	require.True(t, found)
+	require.Same(t, leaf, matchInfo.node)
+
+	testCases := []struct {
+		path            string
+		wantFound       bool
+		wantMatchedPath string
+	}{
+		{path: "/api//v1/order/list/", wantFound: true, wantMatchedPath: "/api/v1/order/list"},
+		{path: "/api/v1/order", wantFound: false},
+		{path: "/api/v1/order/detail", wantFound: false},
+		{path: "/api", wantFound: false},
+		{path: "/api/v1", wantFound: true, wantMatchedPath: "/api/v1"},
+	}
+	for _, tc := range testCases {
+		matchInfo, found := r.FindRoute(http.MethodGet, tc.path)
+		require.Equal(t, tc.wantFound, found, tc.path)
+		if found {
+			require.Equal(t, tc.wantMatchedPath, matchInfo.node.matchedPath)
+		}
+	}
+
+	// 忽略大小写时, 压缩节点的每一段都忽略大小写
+	r.ignoreCase = true
+	matchInfo, found = r.FindRoute(http.MethodGet, "/API/V1/Order/List")
+	require.True(t, found)
+	require.Equal(t, "/api/v1/order/list", matchInfo.node.matchedPath)
+}
+
+// go test -v server/*.go -run TestRouter_FindRouteAllocs
+func TestRouter_FindRouteAllocs(t *testing.T) {
+	r := newBenchRouter()
+	allocs := testing.AllocsPerRun(100, func() {
+		_, _ = r.FindRoute(http.MethodGet, "/api/v2/resource150/list/all")
+	})
+	require.Zero(t, allocs)
+}
@@ -2,13 +2,19 @@ package server
 
 import (
 	"context"
+	"crypto/tls"
+	"errors"
 	"log"
 	"net"
 	"net/http"
+	"net/url"
 	"os"
 	"os/signal"
 	"path"
+	"sort"
 	"strings"
+	"sync"
+	"sync/atomic"
 	"syscall"
 	"time"
 )
@@ -19,8 +25,12 @@ type Server interface {
 	http.Handler
 	Start() error
 	ShutDown() error
+	// Run 启动服务, 直到 ctx 被取消或者服务出错
+	Run(ctx context.Context) error
+	// Shutdown 立即开始优雅退出
+	Shutdown(ctx context.Context) error
 	// AddRoute 添加路由
-	AddRoute(method string, path string, handler HandleFunc, middlewares ...HandleFunc)
+	AddRoute(method string, path string, handler HandleFunc, middlewares ...HandleFunc) *Route
 
 	// Use 添加中间件
 	Use(middlewares ...HandleFunc)
@@ -29,21 +39,97 @@ type Server interface {
 type HTTPServer struct {
 	srv             *http.Server
 	addr            string
+	listener        net.Listener
 	shutDownTimeout time.Duration
-	router          *router
-	middlewares     []HandleFunc
-	tplEngine       TemplateEngine
-	staticHandler   *StaticFileHandler
+
+	// 透传给 http.Server 的超时和限制, 0 表示不限制
+	readTimeout       time.Duration
+	readHeaderTimeout time.Duration
+	writeTimeout      time.Duration
+	idleTimeout       time.Duration
+	maxHeaderBytes    int
+
+	tlsConfig *tls.Config
+	// WithCertFiles 设置的证书文件
+	certFile string
+	keyFile  string
+	// 检查证书文件是否更新的间隔
+	certReloadInterval time.Duration
+	// 允许不加密的 HTTP/2
+	h2c bool
+	// 收到这些信号时平滑重启
+	restartSignals []os.Signal
+
+	// done 在 Serve 返回之后关闭, serveErr 为 Serve 返回的错误
+	done     chan struct{}
+	serveErr error
+	// 退出过程中新的请求返回 503, inflight 为正在处理的请求数
+	draining atomic.Bool
+	inflight atomic.Int64
+	// closing 已经开始退出, 重复调用 Shutdown 时只关闭 http.Server
+	closing atomic.Bool
+	// conns 还没有关闭的连接数, 平滑重启时等待这些连接处理完请求
+	conns   atomic.Int64
+	hooks   []*shutdownHook
+	hooksMu sync.Mutex
+
+	// routes 当前使用的路由表, 修改路由时整体替换, 所以可以在运行时增删路由
+	routes   atomic.Pointer[routeTable]
+	routesMu sync.Mutex
+	// serving 已经开始处理请求, 之后修改路由需要复制路由表
+	serving atomic.Bool
+	// owner ReplaceRoutes 创建影子 server 的 server, published 之后影子 server 的路由读写都交给 owner
+	owner         *HTTPServer
+	published     atomic.Bool
+	middlewares   []HandleFunc
+	tplEngine     TemplateEngine
+	staticHandler *StaticFileHandler
+	// Bind 使用的校验规则和校验错误的格式
+	validator *validator
+	// Bind 和 Negotiate 使用的编解码
+	codecs *codecRegistry
+
+	// 复用 Context, 减少每个请求的内存分配
+	ctxPool      sync.Pool
+	reuseContext bool
+	// 默认缓冲所有的响应, 让中间件可以在 handler 执行之后改写响应
+	bufferResponse bool
+
+	// 路径在其他 method 下存在时, 返回 405 而不是 404
+	handleMethodNotAllowed bool
+	// 自动响应已注册路径的 OPTIONS 请求
+	handleOptions bool
+
+	notFoundHandler         HandleFunc
+	methodNotAllowedHandler HandleFunc
+
+	// 请求路径和注册的路由只差末尾的 / 时重定向
+	redirectTrailingSlash bool
+	// 请求路径中包含 .. 或者 // 时, 清理后重定向
+	redirectFixedPath bool
 }
 
 func New(addr string, opts ...Option) *HTTPServer {
 	serv := &HTTPServer{
-		addr:            addr,
-		shutDownTimeout: time.Second * 15,
-		router:          newRouter(),
-		middlewares:     make([]HandleFunc, 0),
+		addr:               addr,
+		shutDownTimeout:    time.Second * 15,
+		readHeaderTimeout:  time.Second * 10,
+		certReloadInterval: time.Minute,
+		middlewares:        make([]HandleFunc, 0),
+		validator:          newValidator(),
+		codecs:             newCodecRegistry(),
+
+		reuseContext: true,
+
+		handleMethodNotAllowed: true,
+		handleOptions:          true,
+
+		notFoundHandler:         defaultNotFoundHandler,
+		methodNotAllowedHandler: defaultMethodNotAllowedHandler,
 	}
 
+	serv.routes.Store(newRouteTable())
+
 	for _, opt := range opts {
 		opt(serv)
 	}
@@ -56,29 +142,121 @@ func New(addr string, opts ...Option) *HTTPServer {
 }
 
 // AddRoute 添加路由
-func (s *HTTPServer) AddRoute(method string, path string, handler HandleFunc, middlewares ...HandleFunc) {
-	s.router.AddRoute(method, path, s.middlewares, handler, middlewares...)
+func (s *HTTPServer) AddRoute(method string, path string, handler HandleFunc, middlewares ...HandleFunc) *Route {
+	route, err := s.addRoute("", method, path, s.table().router.strict, nil, handler, middlewares...)
+	if err != nil {
+		panic(err)
+	}
+	return route
+}
+
+// AddRouteE 添加路由, 和已注册的路由冲突时返回 *RouteError, 适合从配置中构建路由表
+func (s *HTTPServer) AddRouteE(method string, path string, handler HandleFunc, middlewares ...HandleFunc) (*Route, error) {
+	return s.addRoute("", method, path, true, nil, handler, middlewares...)
+}
+
+// addRoute 添加路由到 host 对应的路由树
+func (s *HTTPServer) addRoute(host string, method string, path string, strict bool, predicates []Predicate, handler HandleFunc, middlewares ...HandleFunc) (*Route, error) {
+	err := s.updateRoutes(func(t *routeTable) error {
+		return t.addRoute(host, method, path, strict, predicates, s.middlewares, handler, middlewares...)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &Route{
+		serv:   s,
+		host:   host,
+		method: method,
+		path:   path,
+	}, nil
+}
+
+// URLFor 根据路由名字和路径参数生成 URL, params 为 key, value 交替出现的路径参数
+// 先查找默认的路由树, 再按注册顺序查找 host 路由树
+func (s *HTTPServer) URLFor(name string, params ...any) (string, error) {
+	t := s.table()
+	u, err := t.router.URLFor(name, params...)
+	if !errors.Is(err, ErrRouteNameNotFound) {
+		return u, err
+	}
+	for _, pattern := range t.hosts.patterns {
+		u, err = t.routerOf(pattern).URLFor(name, params...)
+		if !errors.Is(err, ErrRouteNameNotFound) {
+			return u, err
+		}
+	}
+	return u, err
 }
 
 // ServeHTTP implements Server.
 func (s *HTTPServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
-	ctx := NewContext(r, w, s.tplEngine)
+	s.inflight.Add(1)
+	defer s.inflight.Add(-1)
+	if s.draining.Load() {
+		rejectDraining(w)
+		return
+	}
+	s.markServing()
+	ctx := s.acquireContext(w, r)
 	// 查找路由,并实现命中的路由
 	s.serve(ctx)
+	ctx.writer.flushBuffer()
+	// handler panic 时不回收, ctx 中可能还有没执行完的状态
+	s.releaseContext(ctx)
 }
 
-// TODO:
-func (s *HTTPServer) serve(ctx *Context) {
-	matchInfo, ok := s.router.FindRoute(ctx.Req.Method, ctx.Req.URL.Path)
-	if !ok {
-		// 路由没有找到
-		ctx.Resp.WriteHeader(http.StatusNotFound)
-		_, _ = ctx.Resp.Write([]byte("NOT FOUND"))
+// acquireContext 从 pool 中取出 ctx, 关闭复用时每次新建
+func (s *HTTPServer) acquireContext(w http.ResponseWriter, r *http.Request) *Context {
+	var ctx *Context
+	if s.reuseContext {
+		ctx, _ = s.ctxPool.Get().(*Context)
+	}
+	if ctx != nil {
+		ctx.reset(r, w, s.tplEngine)
+	} else {
+		ctx = NewContext(r, w, s.tplEngine)
+	}
+	ctx.writer.buffered = s.bufferResponse
+	ctx.validator = s.validator
+	ctx.codecs = s.codecs
+	return ctx
+}
+
+// releaseContext 请求结束之后把 ctx 放回 pool, 在被下一个请求取出之前使用这个 ctx 会 panic
+// 被取出之后 reset 会清除标记, 持有的 ctx 读写的就是新请求的数据, 所以这里只是尽力检查, 不能代替 Copy
+func (s *HTTPServer) releaseContext(ctx *Context) {
+	if !s.reuseContext {
 		return
 	}
-	ctx.HandlerChain = matchInfo.node.handlerChains
-	ctx.PathParams = matchInfo.pathParams
-	ctx.MatchedPath = matchInfo.node.matchedPath
+	ctx.released.Store(true)
+	// 不持有请求相关的对象, 避免 pool 中的 ctx 延长它们的生命周期
+	ctx.Req = nil
+	ctx.Resp = nil
+	ctx.writer.ResponseWriter = nil
+	s.ctxPool.Put(ctx)
+}
+
+func (s *HTTPServer) serve(ctx *Context) {
+	r, hostParams := s.table().match(ctx.Req.Host)
+	// 匹配条件都不满足的路由不会命中, 继续尝试其他路由
+	matchInfo, ok := r.findRoute(ctx.Req.Method, ctx.Req.URL.Path, ctx.Req)
+	if target, redirect := s.redirectPath(r, ctx.Req, matchInfo, ok); redirect {
+		ctx.HandlerChain = s.withMiddlewares(redirectHandler(target))
+	} else if ok {
+		ctx.HandlerChain = matchInfo.node.handlerChainsFor(ctx.Req)
+		// 没有路径参数时 pathParams 为 nil, 保留 ctx 中已经初始化的
+		if matchInfo.pathParams != nil {
+			ctx.PathParams = matchInfo.pathParams
+		}
+		ctx.MatchedPath = matchInfo.node.matchedPath
+	} else {
+		// 没有命中路由, 也需要经过全局中间件, 保证日志, 监控和链路追踪能够覆盖到
+		ctx.HandlerChain = s.unmatchedChain(r, ctx)
+	}
+	// host 中的参数和路径参数一起, 通过 ctx.PathValue 获取
+	for _, param := range hostParams {
+		ctx.PathParams.Add(param.key, param.value)
+	}
 	// ctx.FuncName = matchInfo.FuncName
 	// 执行 handler chain
 	for ctx.Index < len(ctx.HandlerChain) {
@@ -87,47 +265,260 @@ func (s *HTTPServer) serve(ctx *Context) {
 	}
 }
 
-// Start implements Server.
+// unmatchedChain 构造没有命中路由时的调用链: 全局中间件 + 404/405/OPTIONS handler
+func (s *HTTPServer) unmatchedChain(r *router, ctx *Context) []HandleFunc {
+	handler := s.notFoundHandler
+	if s.handleMethodNotAllowed || s.handleOptions {
+		allowed := r.AllowedMethods(ctx.Req)
+		if len(allowed) > 0 {
+			if s.handleOptions {
+				allowed = appendMethod(allowed, http.MethodOptions)
+			}
+			allow := strings.Join(allowed, ", ")
+			if s.handleOptions && ctx.Req.Method == http.MethodOptions {
+				// 自动响应 OPTIONS
+				ctx.Resp.Header().Set("Allow", allow)
+				handler = optionsHandler
+			} else if s.handleMethodNotAllowed {
+				ctx.Resp.Header().Set("Allow", allow)
+				handler = s.methodNotAllowedHandler
+			}
+		}
+	}
+	return s.withMiddlewares(handler)
+}
+
+// withMiddlewares 在 handler 前面加上全局中间件
+func (s *HTTPServer) withMiddlewares(handler HandleFunc) []HandleFunc {
+	chain := make([]HandleFunc, 0, len(s.middlewares)+1)
+	chain = append(chain, s.middlewares...)
+	return append(chain, handler)
+}
+
+// redirectPath 根据重定向策略, 计算需要重定向到的路径
+func (s *HTTPServer) redirectPath(r *router, req *http.Request, matchInfo MatchNode, found bool) (target string, redirect bool) {
+	if req.Method == http.MethodConnect || (!s.redirectFixedPath && !s.redirectTrailingSlash) {
+		return "", false
+	}
+	reqPath := req.URL.Path
+	target = reqPath
+	if s.redirectFixedPath {
+		target = cleanPath(reqPath)
+		if !found && target != reqPath {
+			matchInfo, found = r.findRoute(req.Method, target, req)
+		}
+	}
+	if !found {
+		return "", false
+	}
+	if s.redirectTrailingSlash {
+		target = fixTrailingSlash(target, matchInfo.node.matchedPath)
+	}
+	// //evil.com 或者 /\evil.com 会被浏览器当成其他域名, 开头只保留一个 /
+	target = "/" + strings.TrimLeft(target, "/\\")
+	return target, target != reqPath
+}
+
+func redirectHandler(target string) HandleFunc {
+	return func(ctx *Context) {
+		// GET 使用 301, 其他 method 使用 308, 避免客户端把 POST 改成 GET
+		code := http.StatusMovedPermanently
+		if ctx.Req.Method != http.MethodGet && ctx.Req.Method != http.MethodHead {
+			code = http.StatusPermanentRedirect
+		}
+		// target 是解码之后的 path, 需要重新转义, 否则 %3F 这样的字符会变成 query
+		u := url.URL{Path: target, RawQuery: ctx.Req.URL.RawQuery}
+		http.Redirect(ctx.Resp, ctx.Req, u.String(), code)
+	}
+}
+
+// cleanPath 清理 .. 和 //, 保留末尾的 /
+func cleanPath(p string) string {
+	cleaned := path.Clean("/" + p)
+	if strings.HasSuffix(p, "/") && cleaned != "/" {
+		cleaned += "/"
+	}
+	return cleaned
+}
+
+// fixTrailingSlash 让 p 末尾的 / 和注册的路由保持一致
+func fixTrailingSlash(p string, pattern string) string {
+	if pattern == "/" || pattern == "" || strings.HasPrefix(path.Base(pattern), "*") || p == "/" {
+		return p
+	}
+	wantSlash := strings.HasSuffix(pattern, "/")
+	hasSlash := strings.HasSuffix(p, "/")
+	if wantSlash && !hasSlash {
+		return p + "/"
+	}
+	if !wantSlash && hasSlash {
+		return strings.TrimRight(p, "/")
+	}
+	return p
+}
+
+func defaultNotFoundHandler(ctx *Context) {
+	ctx.WriteString(http.StatusNotFound, []byte("NOT FOUND"))
+}
+
+func defaultMethodNotAllowedHandler(ctx *Context) {
+	ctx.WriteString(http.StatusMethodNotAllowed, []byte("METHOD NOT ALLOWED"))
+}
+
+func optionsHandler(ctx *Context) {
+	ctx.Resp.WriteHeader(http.StatusNoContent)
+}
+
+// Start 监听地址并在后台处理请求, 监听失败时返回错误
+// 配置了 WithTLSConfig 或者 WithCertFiles 时使用 HTTPS, 处理请求过程中的错误通过 Wait 获取, 不会直接退出进程
 func (s *HTTPServer) Start() error {
+	if s.certFile != "" {
+		return s.StartTLS(s.certFile, s.keyFile)
+	}
+	return s.start(s.tlsConfig)
+}
 
-	l, err := net.Listen("tcp", s.addr)
+func (s *HTTPServer) start(tlsConfig *tls.Config) error {
+	// 平滑重启启动的子进程, 优先使用父进程传过来的 listener, WithListener 设置的 listener 会被关闭
+	inherited, err := inheritedListener()
 	if err != nil {
 		return err
 	}
-	startCh := make(chan struct{}, 1)
+	if inherited != nil {
+		if s.listener != nil {
+			_ = s.listener.Close()
+		}
+		s.listener = inherited
+	}
+	if s.listener == nil {
+		if s.listener, err = net.Listen("tcp", s.addr); err != nil {
+			return err
+		}
+	}
+	l := s.listener
+	s.srv = &http.Server{
+		Addr:              s.addr,
+		Handler:           s.handler(),
+		TLSConfig:         tlsConfig,
+		ReadTimeout:       s.readTimeout,
+		ReadHeaderTimeout: s.readHeaderTimeout,
+		WriteTimeout:      s.writeTimeout,
+		IdleTimeout:       s.idleTimeout,
+		MaxHeaderBytes:    s.maxHeaderBytes,
+		ConnState:         s.trackConn,
+	}
+	s.done = make(chan struct{})
 	go func() {
-		s.srv = &http.Server{
-			Addr:    s.addr,
-			Handler: s,
+		defer close(s.done)
+		var err error
+		if tlsConfig != nil {
+			// 证书由 tlsConfig 提供, 同时会自动开启 HTTP/2
+			err = s.srv.ServeTLS(l, "", "")
+		} else {
+			err = s.srv.Serve(l)
 		}
-		startCh <- struct{}{}
-		if err = s.srv.Serve(l); err != nil {
-			log.Fatal(err)
+		// 平滑重启时 handoff 直接关闭了 listener
+		if !errors.Is(err, http.ErrServerClosed) && !(s.closing.Load() && errors.Is(err, net.ErrClosed)) {
+			s.serveErr = err
 		}
 	}()
-	<-startCh
-	log.Printf("server already started at address: [%s]\n", s.addr)
+	log.Printf("server already started at address: [%s]\n", l.Addr())
+	notifyReady()
 	return nil
 }
 
+// Addr 返回实际监听的地址, 监听 :0 时可以用来获取随机分配的端口, 没有启动时返回 New 传入的地址
+func (s *HTTPServer) Addr() string {
+	if s.listener == nil {
+		return s.addr
+	}
+	return s.listener.Addr().String()
+}
+
+// Wait 阻塞到服务停止, 返回处理请求过程中的错误, 通过 Shutdown 正常停止时返回 nil
+func (s *HTTPServer) Wait() error {
+	if s.done == nil {
+		return nil
+	}
+	<-s.done
+	return s.serveErr
+}
+
+// Run 启动服务, 直到 ctx 被取消或者服务出错
+// 配置了 WithRestartSignals 时, 收到信号会把 listener 交给新的子进程, 然后当前进程退出
+// ctx 取消之后, 在 shutDownTimeout 之内优雅退出, 例如:
+//
+//	ctx, stop := server.SignalContext(context.Background())
+//	defer stop()
+//	err := serv.Run(ctx)
+func (s *HTTPServer) Run(ctx context.Context) error {
+	// 收到重启信号时平滑重启, 没有配置时 restartCh 为 nil, 永远不会触发
+	// 在 Start 之前监听, 保证开始处理请求之后收到的信号不会直接结束进程
+	var restartCh chan os.Signal
+	if len(s.restartSignals) > 0 {
+		restartCh = make(chan os.Signal, 1)
+		signal.Notify(restartCh, s.restartSignals...)
+		defer signal.Stop(restartCh)
+	}
+	if err := s.Start(); err != nil {
+		return err
+	}
+	restarted := false
+	for running := true; running; {
+		select {
+		case <-s.done:
+			return s.serveErr
+		case <-ctx.Done():
+			running = false
+		case <-restartCh:
+			spawnCtx, cancel := context.WithTimeout(ctx, s.shutDownTimeout)
+			err := s.spawnChild(spawnCtx)
+			cancel()
+			if err != nil {
+				// 子进程启动失败, 继续处理请求
+				log.Printf("graceful restart failed: %v\n", err)
+				continue
+			}
+			running, restarted = false, true
+		}
+	}
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), s.shutDownTimeout)
+	defer cancel()
+	// 重启之后子进程已经在处理请求, 立即停止接收新的连接
+	if err := s.shutdown(shutdownCtx, !restarted); err != nil {
+		return err
+	}
+	return s.Wait()
+}
+
 // ShutDown implements Server.
+// 阻塞到收到 SIGINT 或者 SIGTERM 之后再优雅退出
+//
+// Deprecated: 使用 SignalContext 和 Run, 或者直接调用 Shutdown.
 func (s *HTTPServer) ShutDown() error {
-	// 优雅退出
 	// kill -9 是捕捉不到的
-	quit := make(chan os.Signal, 1)
-	signal.Notify(quit, syscall.SIGINT, syscall.SIGTERM)
-	<-quit
+	ctx, stop := SignalContext(context.Background())
+	defer stop()
+	<-ctx.Done()
 
-	ctx, cancel := context.WithTimeout(context.Background(), s.shutDownTimeout)
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), s.shutDownTimeout)
 	defer cancel()
-	for {
-		select {
-		case <-ctx.Done():
-			break
-		default:
-			return s.srv.Shutdown(ctx)
-		}
+	return s.Shutdown(shutdownCtx)
+}
+
+// Listen 平滑重启启动的子进程中返回父进程传过来的 listener, 否则监听 addr
+// 需要自己创建 listener 再通过 WithListener 传入时, 使用 Listen 代替 net.Listen, 子进程就不会重新绑定端口
+func Listen(network string, addr string) (net.Listener, error) {
+	l, err := inheritedListener()
+	if err != nil || l != nil {
+		return l, err
 	}
+	return net.Listen(network, addr)
+}
+
+// SignalContext 返回收到 SIGINT 或者 SIGTERM 时取消的 ctx, 需要监听信号时显式调用
+func SignalContext(parent context.Context) (context.Context, context.CancelFunc) {
+	return signal.NotifyContext(parent, syscall.SIGINT, syscall.SIGTERM)
 }
 
 // Use implements Server.
@@ -137,50 +528,57 @@ func (s *HTTPServer) Use(middlewares ...HandleFunc) {
 
 // Extension methods
 
-func (s *HTTPServer) Get(path string, handler HandleFunc, middlewares ...HandleFunc) {
-	s.AddRoute(http.MethodGet, path, handler, middlewares...)
+func (s *HTTPServer) Get(path string, handler HandleFunc, middlewares ...HandleFunc) *Route {
+	return s.AddRoute(http.MethodGet, path, handler, middlewares...)
 }
 
-func (s *HTTPServer) Head(path string, handler HandleFunc, middlewares ...HandleFunc) {
-	s.AddRoute(http.MethodHead, path, handler, middlewares...)
+func (s *HTTPServer) Head(path string, handler HandleFunc, middlewares ...HandleFunc) *Route {
+	return s.AddRoute(http.MethodHead, path, handler, middlewares...)
 }
 
-func (s *HTTPServer) Post(path string, handler HandleFunc, middlewares ...HandleFunc) {
-	s.AddRoute(http.MethodPost, path, handler, middlewares...)
+func (s *HTTPServer) Post(path string, handler HandleFunc, middlewares ...HandleFunc) *Route {
+	return s.AddRoute(http.MethodPost, path, handler, middlewares...)
 }
 
-func (s *HTTPServer) Put(path string, handler HandleFunc, middlewares ...HandleFunc) {
-	s.AddRoute(http.MethodPut, path, handler, middlewares...)
+func (s *HTTPServer) Put(path string, handler HandleFunc, middlewares ...HandleFunc) *Route {
+	return s.AddRoute(http.MethodPut, path, handler, middlewares...)
 }
 
-func (s *HTTPServer) Patch(path string, handler HandleFunc, middlewares ...HandleFunc) {
-	s.AddRoute(http.MethodPatch, path, handler, middlewares...)
+func (s *HTTPServer) Patch(path string, handler HandleFunc, middlewares ...HandleFunc) *Route {
+	return s.AddRoute(http.MethodPatch, path, handler, middlewares...)
 }
 
-func (s *HTTPServer) Delete(path string, handler HandleFunc, middlewares ...HandleFunc) {
-	s.AddRoute(http.MethodDelete, path, handler, middlewares...)
+func (s *HTTPServer) Delete(path string, handler HandleFunc, middlewares ...HandleFunc) *Route {
+	return s.AddRoute(http.MethodDelete, path, handler, middlewares...)
 }
 
-func (s *HTTPServer) Connect(path string, handler HandleFunc, middlewares ...HandleFunc) {
-	s.AddRoute(http.MethodConnect, path, handler, middlewares...)
+func (s *HTTPServer) Connect(path string, handler HandleFunc, middlewares ...HandleFunc) *Route {
+	return s.AddRoute(http.MethodConnect, path, handler, middlewares...)
 }
 
-func (s *HTTPServer) Options(path string, handler HandleFunc, middlewares ...HandleFunc) {
-	s.AddRoute(http.MethodOptions, path, handler, middlewares...)
+func (s *HTTPServer) Options(path string, handler HandleFunc, middlewares ...HandleFunc) *Route {
+	return s.AddRoute(http.MethodOptions, path, handler, middlewares...)
 }
 
-func (s *HTTPServer) Trace(path string, handler HandleFunc, middlewares ...HandleFunc) {
-	s.AddRoute(http.MethodTrace, path, handler, middlewares...)
+func (s *HTTPServer) Trace(path string, handler HandleFunc, middlewares ...HandleFunc) *Route {
+	return s.AddRoute(http.MethodTrace, path, handler, middlewares...)
 }
 
 func (s *HTTPServer) ServeStaticDir(relativePath string, dir string) {
-	s.Get(path.Join(relativePath, "*"), func(ctx *Context) {
-		// 找到file
-		file := path.Clean(strings.TrimLeft(ctx.Req.URL.Path, relativePath))
-		handleFunc := s.staticHandler.Handle(dir)
-		ctx.PathParams.Set("file", file)
-		handleFunc(ctx)
-	})
+	// *file 会捕获 relativePath 之后的整个路径
+	s.Get(path.Join("/", relativePath, "*file"), s.staticHandler.Handle(dir))
+}
+
+// appendMethod 添加 method, 已存在时不重复添加
+func appendMethod(methods []string, method string) []string {
+	for _, m := range methods {
+		if m == method {
+			return methods
+		}
+	}
+	methods = append(methods, method)
+	sort.Strings(methods)
+	return methods
 }
 
 var _ Server = (*HTTPServer)(nil)
@@ -0,0 +1,507 @@
+package server
+
+import (
+	"bufio"
+	"context"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+// go test -v server/*.go -run TestHTTPServer_MethodNotAllowed
+func TestHTTPServer_MethodNotAllowed(t *testing.T) {
+
+	handler := func(ctx *Context) {
+		ctx.WriteString(http.StatusOK, []byte("ok"))
+	}
+
+	testCases := []struct {
+		name      string
+		opts      []Option
+		method    string
+		path      string
+		wantCode  int
+		wantAllow string
+	}{
+		{
+			name:      "method not allowed",
+			method:    http.MethodDelete,
+			path:      "/user/1",
+			wantCode:  http.StatusMethodNotAllowed,
+			wantAllow: "GET, OPTIONS, POST",
+		},
+		{
+			name:      "auto options",
+			method:    http.MethodOptions,
+			path:      "/user/1",
+			wantCode:  http.StatusNoContent,
+			wantAllow: "GET, OPTIONS, POST",
+		},
+		{
+			name:     "registered options",
+			method:   http.MethodOptions,
+			path:     "/login",
+			wantCode: http.StatusOK,
+		},
+		{
+			name:      "registered options should be listed once",
+			method:    http.MethodGet,
+			path:      "/login",
+			wantCode:  http.StatusMethodNotAllowed,
+			wantAllow: "OPTIONS, POST",
+		},
+		{
+			name:     "path not exists",
+			method:   http.MethodGet,
+			path:     "/order/1",
+			wantCode: http.StatusNotFound,
+		},
+		{
+			name:      "method not allowed disabled",
+			opts:      []Option{WithMethodNotAllowed(false)},
+			method:    http.MethodDelete,
+			path:      "/user/1",
+			wantCode:  http.StatusNotFound,
+			wantAllow: "",
+		},
+		{
+			name:      "auto options disabled",
+			opts:      []Option{WithAutoOptions(false)},
+			method:    http.MethodOptions,
+			path:      "/user/1",
+			wantCode:  http.StatusMethodNotAllowed,
+			wantAllow: "GET, POST",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			serv := New(":8081", tc.opts...)
+			serv.Get("/user/:id", handler)
+			serv.Post("/user/:id", handler)
+			serv.Post("/login", handler)
+			serv.Options("/login", handler)
+
+			resp := httptest.NewRecorder()
+			req := httptest.NewRequest(tc.method, tc.path, nil)
+			serv.ServeHTTP(resp, req)
+			require.Equal(t, tc.wantCode, resp.Code)
+			require.Equal(t, tc.wantAllow, resp.Header().Get("Allow"))
+		})
+	}
+}
+
+// go test -v server/*.go -run TestHTTPServer_UnmatchedHandler
+func TestHTTPServer_UnmatchedHandler(t *testing.T) {
+
+	var statuses []int
+	serv := New(":8081",
+		WithNotFoundHandler(func(ctx *Context) {
+			ctx.JSON(http.StatusNotFound, map[string]any{"msg": "not found"})
+		}),
+		WithMethodNotAllowedHandler(func(ctx *Context) {
+			ctx.JSON(http.StatusMethodNotAllowed, map[string]any{"msg": "method not allowed"})
+		}),
+	)
+	serv.Use(func(ctx *Context) {
+		ctx.Next()
+		statuses = append(statuses, ctx.Status())
+	})
+	serv.Get("/user", func(ctx *Context) {
+		ctx.JSON(http.StatusOK, map[string]any{"msg": "ok"})
+	})
+
+	testCases := []struct {
+		name      string
+		method    string
+		path      string
+		wantCode  int
+		wantBody  string
+		wantAllow string
+	}{
+		{
+			name:     "not found",
+			method:   http.MethodGet,
+			path:     "/order",
+			wantCode: http.StatusNotFound,
+			wantBody: `{"msg":"not found"}`,
+		},
+		{
+			name:      "method not allowed",
+			method:    http.MethodPost,
+			path:      "/user",
+			wantCode:  http.StatusMethodNotAllowed,
+			wantBody:  `{"msg":"method not allowed"}`,
+			wantAllow: "GET, OPTIONS",
+		},
+		{
+			name:      "auto options",
+			method:    http.MethodOptions,
+			path:      "/user",
+			wantCode:  http.StatusNoContent,
+			wantAllow: "GET, OPTIONS",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			statuses = nil
+			resp := httptest.NewRecorder()
+			req := httptest.NewRequest(tc.method, tc.path, nil)
+			serv.ServeHTTP(resp, req)
+			require.Equal(t, tc.wantCode, resp.Code)
+			require.Equal(t, tc.wantBody, resp.Body.String())
+			require.Equal(t, tc.wantAllow, resp.Header().Get("Allow"))
+			// 全局中间件能够拿到状态码
+			require.Equal(t, []int{tc.wantCode}, statuses)
+		})
+	}
+}
+
+// go test -v server/*.go -run TestHTTPServer_Redirect
+func TestHTTPServer_Redirect(t *testing.T) {
+
+	handler := func(ctx *Context) {
+		ctx.WriteString(http.StatusOK, []byte(ctx.MatchedPath))
+	}
+
+	testCases := []struct {
+		name         string
+		opts         []Option
+		method       string
+		path         string
+		wantCode     int
+		wantLocation string
+		wantBody     string
+	}{
+		{
+			name:     "lenient by default",
+			method:   http.MethodGet,
+			path:     "/user//1/",
+			wantCode: http.StatusOK,
+			wantBody: "/user/:id",
+		},
+		{
+			name:         "remove trailing slash",
+			opts:         []Option{WithRedirectTrailingSlash(true)},
+			method:       http.MethodGet,
+			path:         "/user/1/",
+			wantCode:     http.StatusMovedPermanently,
+			wantLocation: "/user/1",
+		},
+		{
+			name:         "add trailing slash with query",
+			opts:         []Option{WithRedirectTrailingSlash(true)},
+			method:       http.MethodGet,
+			path:         "/docs?page=1",
+			wantCode:     http.StatusMovedPermanently,
+			wantLocation: "/docs/?page=1",
+		},
+		{
+			name:         "escape redirect target",
+			opts:         []Option{WithRedirectTrailingSlash(true)},
+			method:       http.MethodGet,
+			path:         "/user/a%3Fb=c%20d/?page=1",
+			wantCode:     http.StatusMovedPermanently,
+			wantLocation: "/user/a%3Fb=c%20d?page=1",
+		},
+		{
+			name:         "permanent redirect for post",
+			opts:         []Option{WithRedirectTrailingSlash(true)},
+			method:       http.MethodPost,
+			path:         "/user/1/",
+			wantCode:     http.StatusPermanentRedirect,
+			wantLocation: "/user/1",
+		},
+		{
+			name:     "star route keeps trailing slash",
+			opts:     []Option{WithRedirectTrailingSlash(true)},
+			method:   http.MethodGet,
+			path:     "/static/css/",
+			wantCode: http.StatusOK,
+			wantBody: "/static/*",
+		},
+		{
+			name:     "catch-all matches empty remainder",
+			opts:     []Option{WithRedirectTrailingSlash(true)},
+			method:   http.MethodGet,
+			path:     "/assets/",
+			wantCode: http.StatusOK,
+			wantBody: "/assets/*filepath",
+		},
+		{
+			name:         "clean path",
+			opts:         []Option{WithRedirectFixedPath(true)},
+			method:       http.MethodGet,
+			path:         "/order/../user//1",
+			wantCode:     http.StatusMovedPermanently,
+			wantLocation: "/user/1",
+		},
+		{
+			name:         "clean path and trailing slash",
+			opts:         []Option{WithRedirectFixedPath(true), WithRedirectTrailingSlash(true)},
+			method:       http.MethodGet,
+			path:         "/user/./1//",
+			wantCode:     http.StatusMovedPermanently,
+			wantLocation: "/user/1",
+		},
+		{
+			name:     "clean path not found",
+			opts:     []Option{WithRedirectFixedPath(true)},
+			method:   http.MethodGet,
+			path:     "/user/../order",
+			wantCode: http.StatusNotFound,
+		},
+		{
+			name:     "case sensitive by default",
+			method:   http.MethodGet,
+			path:     "/USER/1",
+			wantCode: http.StatusNotFound,
+		},
+		{
+			name:     "case insensitive",
+			opts:     []Option{WithCaseInsensitive(true)},
+			method:   http.MethodGet,
+			path:     "/USER/1",
+			wantCode: http.StatusOK,
+			wantBody: "/user/:id",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			serv := New(":8081", tc.opts...)
+			serv.Get("/user/:id", handler)
+			serv.Post("/user/:id", handler)
+			serv.Get("/docs/", handler)
+			serv.Get("/static/*", handler)
+			serv.Get("/assets/*filepath", handler)
+
+			resp := httptest.NewRecorder()
+			req := httptest.NewRequest(tc.method, tc.path, nil)
+			serv.ServeHTTP(resp, req)
+			require.Equal(t, tc.wantCode, resp.Code)
+			require.Equal(t, tc.wantLocation, resp.Header().Get("Location"))
+			if tc.wantBody != "" {
+				require.Equal(t, tc.wantBody, resp.Body.String())
+			}
+		})
+	}
+}
+
+// go test -v server/*.go -run TestHTTPServer_RedirectOpenRedirect
+func TestHTTPServer_RedirectOpenRedirect(t *testing.T) {
+	testCases := []struct {
+		name         string
+		opts         []Option
+		path         string
+		wantLocation string
+	}{
+		{
+			name:         "trailing slash",
+			opts:         []Option{WithRedirectTrailingSlash(true)},
+			path:         "//evil.com/",
+			wantLocation: "/evil.com",
+		},
+		{
+			name:         "backslash",
+			opts:         []Option{WithRedirectTrailingSlash(true)},
+			path:         "/%5Cevil.com/",
+			wantLocation: "/evil.com",
+		},
+		{
+			name:         "fixed path",
+			opts:         []Option{WithRedirectFixedPath(true), WithRedirectTrailingSlash(true)},
+			path:         "//evil.com//",
+			wantLocation: "/evil.com",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			serv := New(":8081", tc.opts...)
+			serv.Get("/:x", func(ctx *Context) {
+				ctx.WriteString(http.StatusOK, []byte(ctx.PathValue("x").val))
+			})
+
+			resp := httptest.NewRecorder()
+			serv.ServeHTTP(resp, httptest.NewRequest(http.MethodGet, tc.path, nil))
+			require.Equal(t, http.StatusMovedPermanently, resp.Code)
+			require.Equal(t, tc.wantLocation, resp.Header().Get("Location"))
+		})
+	}
+}
+
+// go test -v server/*.go -run TestHTTPServer_ServeStaticDir
+func TestHTTPServer_ServeStaticDir(t *testing.T) {
+	_, filePath, _, _ := runtime.Caller(0)
+	staticDir := path.Join(path.Dir(filePath), "testdata", "static")
+
+	serv := New(":8081")
+	serv.ServeStaticDir("/static", staticDir)
+
+	testCases := []struct {
+		name     string
+		path     string
+		wantCode int
+		wantFile string
+	}{
+		{
+			name:     "file in dir",
+			path:     "/static/avatar.jpeg",
+			wantCode: http.StatusOK,
+			wantFile: "avatar.jpeg",
+		},
+		{
+			name:     "file in sub dir",
+			path:     "/static/image/avatar.jpeg",
+			wantCode: http.StatusOK,
+			wantFile: "image/avatar.jpeg",
+		},
+		{
+			name:     "file not exists",
+			path:     "/static/image/unknown.jpeg",
+			wantCode: http.StatusInternalServerError,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			resp := httptest.NewRecorder()
+			req := httptest.NewRequest(http.MethodGet, tc.path, nil)
+			serv.ServeHTTP(resp, req)
+			require.Equal(t, tc.wantCode, resp.Code)
+			if tc.wantFile == "" {
+				return
+			}
+			data, err := os.ReadFile(path.Join(staticDir, tc.wantFile))
+			require.NoError(t, err)
+			require.Equal(t, data, resp.Body.Bytes())
+			require.Equal(t, "image/jpeg", resp.Header().Get("Content-Type"))
+		})
+	}
+}
+
+// go test -v server/*.go -run TestHTTPServer_Lifecycle
+func TestHTTPServer_Lifecycle(t *testing.T) {
+
+	t.Run("run until context canceled", func(t *testing.T) {
+		serv := New("127.0.0.1:0")
+		ctx, cancel := context.WithCancel(context.Background())
+		errCh := make(chan error, 1)
+		go func() {
+			errCh <- serv.Run(ctx)
+		}()
+		cancel()
+		select {
+		case err := <-errCh:
+			require.NoError(t, err)
+		case <-time.After(time.Second * 5):
+			t.Fatal("server not stopped")
+		}
+	})
+
+	t.Run("listen error", func(t *testing.T) {
+		l, err := net.Listen("tcp", "127.0.0.1:0")
+		require.NoError(t, err)
+		defer l.Close()
+
+		serv := New(l.Addr().String())
+		require.Error(t, serv.Start())
+		require.Error(t, serv.Run(context.Background()))
+	})
+
+	t.Run("shutdown immediately", func(t *testing.T) {
+		serv := New("127.0.0.1:0")
+		require.NoError(t, serv.Start())
+		require.NoError(t, serv.Shutdown(context.Background()))
+		require.NoError(t, serv.Wait())
+	})
+
+	t.Run("shutdown before start", func(t *testing.T) {
+		serv := New("127.0.0.1:0")
+		require.NoError(t, serv.Shutdown(context.Background()))
+		require.NoError(t, serv.Wait())
+	})
+}
+
+// go test -v server/*.go -run TestHTTPServer_Limits
+func TestHTTPServer_Limits(t *testing.T) {
+
+	t.Run("unix socket listener", func(t *testing.T) {
+		sock := filepath.Join(t.TempDir(), "jungle.sock")
+		l, err := net.Listen("unix", sock)
+		require.NoError(t, err)
+
+		serv := New(":8081", WithListener(l))
+		serv.Get("/ping", func(ctx *Context) {
+			ctx.WriteString(http.StatusOK, []byte("pong"))
+		})
+		require.NoError(t, serv.Start())
+		defer func() {
+			require.NoError(t, serv.Shutdown(context.Background()))
+		}()
+		require.Equal(t, sock, serv.Addr())
+
+		client := &http.Client{
+			Transport: &http.Transport{
+				DialContext: func(ctx context.Context, _, _ string) (net.Conn, error) {
+					var d net.Dialer
+					return d.DialContext(ctx, "unix", sock)
+				},
+			},
+		}
+		resp, err := client.Get("http://jungle/ping")
+		require.NoError(t, err)
+		defer resp.Body.Close()
+		body, err := io.ReadAll(resp.Body)
+		require.NoError(t, err)
+		require.Equal(t, "pong", string(body))
+	})
+
+	t.Run("read header timeout", func(t *testing.T) {
+		serv := New("127.0.0.1:0", WithReadHeaderTimeout(time.Millisecond*50))
+		require.NoError(t, serv.Start())
+		defer func() {
+			require.NoError(t, serv.Shutdown(context.Background()))
+		}()
+
+		conn, err := net.Dial("tcp", serv.Addr())
+		require.NoError(t, err)
+		defer conn.Close()
+		// 请求头一直没有发送完, 连接会被服务端关闭
+		_, err = conn.Write([]byte("GET / HTTP/1.1\r\nHost: localhost\r\n"))
+		require.NoError(t, err)
+		require.NoError(t, conn.SetReadDeadline(time.Now().Add(time.Second*5)))
+		_, err = io.ReadAll(conn)
+		require.NoError(t, err)
+	})
+
+	t.Run("max header bytes", func(t *testing.T) {
+		serv := New("127.0.0.1:0", WithMaxHeaderBytes(1024))
+		require.NoError(t, serv.Start())
+		defer func() {
+			require.NoError(t, serv.Shutdown(context.Background()))
+		}()
+
+		conn, err := net.Dial("tcp", serv.Addr())
+		require.NoError(t, err)
+		defer conn.Close()
+		_, err = conn.Write([]byte("GET / HTTP/1.1\r\nHost: localhost\r\nX-Large: " + strings.Repeat("a", 8192) + "\r\n\r\n"))
+		require.NoError(t, err)
+		resp, err := http.ReadResponse(bufio.NewReader(conn), nil)
+		require.NoError(t, err)
+		defer resp.Body.Close()
+		require.Equal(t, http.StatusRequestHeaderFieldsTooLarge, resp.StatusCode)
+	})
+}
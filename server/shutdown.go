@@ -0,0 +1,170 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"time"
+)
+
+// ShutdownHook 退出时执行的清理逻辑, 例如关闭 redis 客户端, 刷新链路追踪的数据
+type ShutdownHook func(ctx context.Context) error
+
+type shutdownHook struct {
+	name    string
+	fn      ShutdownHook
+	timeout time.Duration
+}
+
+// HookOption 退出钩子的配置
+type HookOption func(h *shutdownHook)
+
+// WithHookTimeout 单个钩子的超时时间, 默认并且最多为 WithShutdownTimeout 设置的时间
+func WithHookTimeout(timeout time.Duration) HookOption {
+	return func(h *shutdownHook) {
+		h.timeout = timeout
+	}
+}
+
+// OnShutdown 注册退出钩子, 在请求处理完成, http.Server 关闭之后按注册顺序执行
+// 某个钩子失败不会影响之后的钩子, 所有的错误在 Shutdown 中一起返回
+func (s *HTTPServer) OnShutdown(name string, fn ShutdownHook, opts ...HookOption) {
+	hook := &shutdownHook{
+		name: name,
+		fn:   fn,
+	}
+	for _, opt := range opts {
+		opt(hook)
+	}
+	s.hooksMu.Lock()
+	defer s.hooksMu.Unlock()
+	s.hooks = append(s.hooks, hook)
+}
+
+// Shutdown 立即开始优雅退出:
+//  1. 新的请求返回 503, 等待正在处理的请求结束
+//  2. 关闭 http.Server, 不再接收新的连接
+//  3. 按注册顺序执行退出钩子, 每个钩子使用独立的 ctx, ctx 超时之后钩子仍然有时间清理资源
+//
+// 所有阶段的错误通过 errors.Join 合并返回, 重复调用时只会关闭 http.Server
+func (s *HTTPServer) Shutdown(ctx context.Context) error {
+	return s.shutdown(ctx, true)
+}
+
+// shutdown reject 为 false 时跳过返回 503 的阶段, 直接关闭 listener, 由 http.Server 等待正在处理的请求
+// 平滑重启时子进程已经在同一个 socket 上接收连接, 当前进程需要立即停止接收, 否则新的连接会收到 503
+func (s *HTTPServer) shutdown(ctx context.Context, reject bool) error {
+	if !s.closing.CompareAndSwap(false, true) {
+		return s.shutdownServer(ctx)
+	}
+
+	var errs []error
+	if reject {
+		s.draining.Store(true)
+		if err := s.drain(ctx); err != nil {
+			errs = append(errs, fmt.Errorf("drain requests: %w", err))
+		}
+		if err := s.shutdownServer(ctx); err != nil {
+			errs = append(errs, fmt.Errorf("shutdown http server: %w", err))
+		}
+	} else if err := s.handoff(ctx); err != nil {
+		errs = append(errs, fmt.Errorf("handoff connections: %w", err))
+	}
+
+	s.hooksMu.Lock()
+	hooks := append([]*shutdownHook{}, s.hooks...)
+	s.hooksMu.Unlock()
+	for _, hook := range hooks {
+		if err := hook.run(ctx, s.shutDownTimeout); err != nil {
+			errs = append(errs, fmt.Errorf("shutdown hook %s: %w", hook.name, err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+func (s *HTTPServer) shutdownServer(ctx context.Context) error {
+	if s.srv == nil {
+		return nil
+	}
+	return s.srv.Shutdown(ctx)
+}
+
+// drain 等待正在处理的请求结束, ctx 超时时返回错误
+func (s *HTTPServer) drain(ctx context.Context) error {
+	ticker := time.NewTicker(10 * time.Millisecond)
+	defer ticker.Stop()
+	for s.inflight.Load() > 0 {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+		}
+	}
+	return nil
+}
+
+// handoff 平滑重启时停止接收新的连接, 等已经建立的连接处理完请求之后再关闭 http.Server
+// http.Server.Shutdown 开始之后读到的请求会被直接断开, 所以要等连接都关闭之后才能调用
+func (s *HTTPServer) handoff(ctx context.Context) error {
+	if s.srv == nil {
+		return nil
+	}
+	// 关闭空闲的连接, 正在处理请求的连接在响应之后关闭, 客户端重新建立的连接由子进程处理
+	s.srv.SetKeepAlivesEnabled(false)
+	_ = s.listener.Close()
+	// Serve 返回之后不会再有新的连接
+	select {
+	case <-s.done:
+	case <-ctx.Done():
+		return errors.Join(ctx.Err(), s.srv.Close())
+	}
+	ticker := time.NewTicker(10 * time.Millisecond)
+	defer ticker.Stop()
+	for s.conns.Load() > 0 {
+		select {
+		case <-ctx.Done():
+			return errors.Join(ctx.Err(), s.srv.Close())
+		case <-ticker.C:
+		}
+	}
+	return s.srv.Shutdown(ctx)
+}
+
+// trackConn 用作 http.Server.ConnState, 记录还没有关闭的连接数
+func (s *HTTPServer) trackConn(_ net.Conn, state http.ConnState) {
+	switch state {
+	case http.StateNew:
+		s.conns.Add(1)
+	case http.StateHijacked, http.StateClosed:
+		s.conns.Add(-1)
+	}
+}
+
+// run 执行钩子, 钩子 panic 时转换成错误
+// drain 超时之后 ctx 已经取消, 所以每个钩子使用新的 ctx, 只保留 ctx 中的值, 超时时间最多为 maxTimeout
+func (h *shutdownHook) run(ctx context.Context, maxTimeout time.Duration) (err error) {
+	timeout := h.timeout
+	if maxTimeout > 0 && (timeout <= 0 || timeout > maxTimeout) {
+		timeout = maxTimeout
+	}
+	ctx = context.WithoutCancel(ctx)
+	if timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, timeout)
+		defer cancel()
+	}
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("panic: %v", r)
+		}
+	}()
+	return h.fn(ctx)
+}
+
+// rejectDraining 退出过程中拒绝新的请求, 让负载均衡把流量切到其他实例
+func rejectDraining(w http.ResponseWriter) {
+	w.Header().Set("Connection", "close")
+	w.WriteHeader(http.StatusServiceUnavailable)
+}
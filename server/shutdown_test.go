@@ -0,0 +1,143 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+// go test -v server/*.go -run TestHTTPServer_OnShutdown
+func TestHTTPServer_OnShutdown(t *testing.T) {
+
+	var logs []string
+	errFlush := errors.New("flush failed")
+
+	serv := New(":8081")
+	serv.OnShutdown("collector", func(ctx context.Context) error {
+		logs = append(logs, "collector")
+		return errFlush
+	})
+	serv.OnShutdown("jobs", func(ctx context.Context) error {
+		logs = append(logs, "jobs")
+		<-ctx.Done()
+		return ctx.Err()
+	}, WithHookTimeout(time.Millisecond*10))
+	serv.OnShutdown("redis", func(ctx context.Context) error {
+		logs = append(logs, "redis")
+		panic("closed twice")
+	})
+	serv.OnShutdown("session", func(ctx context.Context) error {
+		logs = append(logs, "session")
+		return nil
+	})
+
+	err := serv.Shutdown(context.Background())
+	require.Equal(t, []string{"collector", "jobs", "redis", "session"}, logs)
+	require.ErrorIs(t, err, errFlush)
+	require.ErrorIs(t, err, context.DeadlineExceeded)
+	require.ErrorContains(t, err, "shutdown hook redis: panic: closed twice")
+
+	// 重复调用不会再执行钩子
+	logs = nil
+	require.NoError(t, serv.Shutdown(context.Background()))
+	require.Nil(t, logs)
+}
+
+// go test -v server/*.go -run TestHTTPServer_Drain
+func TestHTTPServer_Drain(t *testing.T) {
+
+	started := make(chan struct{})
+	release := make(chan struct{})
+	serv := New(":8081")
+	serv.Get("/slow", func(ctx *Context) {
+		close(started)
+		<-release
+		ctx.WriteString(http.StatusOK, []byte("done"))
+	})
+	serv.Get("/fast", func(ctx *Context) {
+		ctx.WriteString(http.StatusOK, []byte("done"))
+	})
+
+	slowResp := httptest.NewRecorder()
+	slowDone := make(chan struct{})
+	go func() {
+		defer close(slowDone)
+		serv.ServeHTTP(slowResp, httptest.NewRequest(http.MethodGet, "/slow", nil))
+	}()
+	<-started
+
+	hookCalled := make(chan struct{})
+	serv.OnShutdown("after drain", func(ctx context.Context) error {
+		// 钩子在正在处理的请求结束之后才执行
+		select {
+		case <-slowDone:
+		default:
+			t.Error("hook called before in-flight request finished")
+		}
+		close(hookCalled)
+		return nil
+	})
+	shutdownErr := make(chan error, 1)
+	go func() {
+		shutdownErr <- serv.Shutdown(context.Background())
+	}()
+
+	// 等待进入 drain 阶段
+	require.Eventually(t, serv.draining.Load, time.Second, time.Millisecond)
+	resp := httptest.NewRecorder()
+	serv.ServeHTTP(resp, httptest.NewRequest(http.MethodGet, "/fast", nil))
+	require.Equal(t, http.StatusServiceUnavailable, resp.Code)
+	require.Equal(t, "close", resp.Header().Get("Connection"))
+
+	close(release)
+	require.NoError(t, <-shutdownErr)
+	<-hookCalled
+	require.Equal(t, "done", slowResp.Body.String())
+}
+
+// go test -v server/*.go -run TestHTTPServer_DrainTimeout
+func TestHTTPServer_DrainTimeout(t *testing.T) {
+
+	release := make(chan struct{})
+	defer close(release)
+	started := make(chan struct{})
+	serv := New(":8081")
+	serv.Get("/slow", func(ctx *Context) {
+		close(started)
+		<-release
+	})
+	go serv.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/slow", nil))
+	<-started
+
+	type ctxKey struct{}
+	var hookCalled bool
+	var hookErr error
+	var hookVal any
+	serv.OnShutdown("cleanup", func(ctx context.Context) error {
+		hookCalled = true
+		hookErr = ctx.Err()
+		hookVal = ctx.Value(ctxKey{})
+		return nil
+	})
+	var deadline time.Duration
+	serv.OnShutdown("flush", func(ctx context.Context) error {
+		d, _ := ctx.Deadline()
+		deadline = time.Until(d)
+		return nil
+	}, WithHookTimeout(time.Second))
+	ctx, cancel := context.WithTimeout(context.WithValue(context.Background(), ctxKey{}, "val"), time.Millisecond*50)
+	defer cancel()
+	err := serv.Shutdown(ctx)
+	require.ErrorIs(t, err, context.DeadlineExceeded)
+	// 超时之后仍然执行钩子, 钩子拿到的是新的 ctx, 尽量释放资源
+	require.True(t, hookCalled)
+	require.NoError(t, hookErr)
+	require.Equal(t, "val", hookVal)
+	require.Greater(t, deadline, time.Millisecond*500)
+	require.LessOrEqual(t, deadline, time.Second)
+}
@@ -0,0 +1,148 @@
+package server
+
+// routeTable 路由表, 发布之后只读
+// 修改路由时复制一份新的路由表, 修改完成之后再原子地替换, 正在处理的请求继续使用旧的路由表
+type routeTable struct {
+	router *router
+	hosts  *hostRouter
+}
+
+func newRouteTable() *routeTable {
+	return &routeTable{
+		router: newRouter(),
+		hosts:  newHostRouter(),
+	}
+}
+
+// clone 深拷贝路由树, handler 和正则是只读的, 可以共享
+func (t *routeTable) clone() *routeTable {
+	return &routeTable{
+		router: t.router.clone(),
+		hosts:  t.hosts.clone(),
+	}
+}
+
+// routerOf 返回 host 对应的路由树, host 为空时返回默认的路由树, host 没有注册过路由时返回 nil
+func (t *routeTable) routerOf(host string) *router {
+	if host == "" {
+		return t.router
+	}
+	return t.hosts.Router(host)
+}
+
+// addRoute 添加路由, host 第一次注册路由时创建对应的路由树
+// 注册失败时不会留下空的 host 路由树, 否则这个 host 的请求不会再使用默认的路由树
+func (t *routeTable) addRoute(host string, method string, path string, strict bool, predicates []Predicate,
+	servMiddlewares []HandleFunc, handler HandleFunc, middlewares ...HandleFunc) error {
+	r := t.routerOf(host)
+	created := r == nil
+	if created {
+		r = newRouter()
+		r.ignoreCase = t.router.ignoreCase
+		r.strict = t.router.strict
+	}
+	if _, err := r.addRoute(method, path, strict, predicates, servMiddlewares, handler, middlewares...); err != nil {
+		return err
+	}
+	if created {
+		return t.hosts.Add(host, r)
+	}
+	return nil
+}
+
+// match 根据请求的 host 选择路由树
+func (t *routeTable) match(host string) (*router, []pathParam) {
+	if r, params, ok := t.hosts.Match(host); ok {
+		return r, params
+	}
+	return t.router, nil
+}
+
+// table 返回当前正在使用的路由表
+func (s *HTTPServer) table() *routeTable {
+	if s.published.Load() {
+		return s.owner.table()
+	}
+	return s.routes.Load()
+}
+
+// updateRoutes 开始处理请求之后, 在当前路由表的副本上执行 fn, 成功之后发布新的路由表
+// 处理请求之前没有读者, 直接修改当前的路由表, 避免注册大量路由时每次都复制整个路由表
+// 所以 fn 需要先校验, 返回错误时不能已经修改了路由表
+func (s *HTTPServer) updateRoutes(fn func(t *routeTable) error) error {
+	s.routesMu.Lock()
+	if s.published.Load() {
+		s.routesMu.Unlock()
+		return s.owner.updateRoutes(fn)
+	}
+	defer s.routesMu.Unlock()
+	t := s.table()
+	if s.serving.Load() {
+		t = t.clone()
+	}
+	if err := fn(t); err != nil {
+		return err
+	}
+	s.routes.Store(t)
+	return nil
+}
+
+// markServing 第一个请求之前调用, 之后修改路由都会复制路由表
+// 持有 routesMu, 保证正在原地修改的路由在请求读取路由表之前完成
+func (s *HTTPServer) markServing() {
+	if s.serving.Load() {
+		return
+	}
+	s.routesMu.Lock()
+	s.serving.Store(true)
+	s.routesMu.Unlock()
+}
+
+// ReplaceRoutes 在一个新的空路由表上执行 register, 成功之后原子地替换当前的路由表
+// 适合插件或者功能开关需要整体重建路由的场景, register 中通过参数 s 注册路由
+// 影子 server 不处理请求, 注册路由时不复制路由表, 运行时批量注册大量路由也应该使用 ReplaceRoutes
+// 返回错误时当前的路由表保持不变, 成功之后 register 中创建的分组和路由修改的是 s 的路由表
+func (s *HTTPServer) ReplaceRoutes(register func(s *HTTPServer) error) error {
+	s.routesMu.Lock()
+	defer s.routesMu.Unlock()
+	t := newRouteTable()
+	t.router.ignoreCase = s.table().router.ignoreCase
+	t.router.strict = s.table().router.strict
+
+	// 在影子 server 上注册, 注册过程中的路由不会被请求看到
+	shadow := &HTTPServer{
+		middlewares:   s.middlewares,
+		staticHandler: s.staticHandler,
+		owner:         s,
+	}
+	shadow.routes.Store(t)
+	if err := register(shadow); err != nil {
+		return err
+	}
+	// 发布之后路由表可能正在被请求读取, 影子 server 不能再原地修改
+	shadow.routesMu.Lock()
+	s.routes.Store(t)
+	shadow.published.Store(true)
+	shadow.routesMu.Unlock()
+	return nil
+}
+
+// RemoveRoute 删除默认路由树中 method 和 path 对应的路由, 包括带匹配条件的路由
+func (s *HTTPServer) RemoveRoute(method string, path string) error {
+	return s.removeRoute("", method, path)
+}
+
+// RemoveRoute 删除分组中的路由, path 是相对于分组前缀的路径
+func (g *RouterGroup) RemoveRoute(method string, path string) error {
+	return g.serv.removeRoute(g.host, method, joinPath(g.prefix, path))
+}
+
+func (s *HTTPServer) removeRoute(host string, method string, path string) error {
+	return s.updateRoutes(func(t *routeTable) error {
+		r := t.routerOf(host)
+		if r == nil {
+			return newRouteError(ErrRouteNotFound, method, path, "")
+		}
+		return r.removeRoute(method, path)
+	})
+}
@@ -0,0 +1,258 @@
+package server
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+// go test -v server/*.go -run TestHTTPServer_RemoveRoute
+func TestHTTPServer_RemoveRoute(t *testing.T) {
+
+	handler := func(ctx *Context) {
+		ctx.WriteString(http.StatusOK, []byte(ctx.MatchedPath))
+	}
+
+	serv := New(":8081")
+	serv.Get("/user/:id", handler).Name("user")
+	serv.Get("/user/:id/profile", handler)
+	serv.When(Header("X-API-Version", "2")).Get("/order", handler)
+	serv.Get("/order", handler)
+	serv.Host("api.example.com").Get("/user", handler)
+
+	require.NoError(t, serv.RemoveRoute(http.MethodGet, "/user/:id"))
+	require.NoError(t, serv.RemoveRoute(http.MethodGet, "/order"))
+	require.NoError(t, serv.Host("api.example.com").RemoveRoute(http.MethodGet, "/user"))
+
+	err := serv.RemoveRoute(http.MethodGet, "/user/:id")
+	require.ErrorIs(t, err, ErrRouteNotFound)
+	err = serv.RemoveRoute(http.MethodPost, "/user/:id/profile")
+	require.ErrorIs(t, err, ErrRouteNotFound)
+
+	_, err = serv.URLFor("user", "id", 1)
+	require.ErrorIs(t, err, ErrRouteNameNotFound)
+	require.Len(t, serv.Routes(), 1)
+
+	testCases := []struct {
+		name     string
+		host     string
+		path     string
+		wantCode int
+	}{
+		{
+			name:     "removed",
+			path:     "/user/1",
+			wantCode: http.StatusNotFound,
+		},
+		{
+			name:     "child of removed",
+			path:     "/user/1/profile",
+			wantCode: http.StatusOK,
+		},
+		{
+			name:     "removed with predicates",
+			path:     "/order",
+			wantCode: http.StatusNotFound,
+		},
+		{
+			name:     "removed from host",
+			host:     "api.example.com",
+			path:     "/user",
+			wantCode: http.StatusNotFound,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			resp := httptest.NewRecorder()
+			req := httptest.NewRequest(http.MethodGet, tc.path, nil)
+			if tc.host != "" {
+				req.Host = tc.host
+			}
+			serv.ServeHTTP(resp, req)
+			require.Equal(t, tc.wantCode, resp.Code)
+		})
+	}
+}
+
+// go test -v server/*.go -run TestHTTPServer_ReplaceRoutes
+func TestHTTPServer_ReplaceRoutes(t *testing.T) {
+
+	handler := func(ctx *Context) {
+		ctx.WriteString(http.StatusOK, []byte(ctx.MatchedPath))
+	}
+
+	serv := New(":8081")
+	serv.Get("/v1/user", handler)
+
+	// 注册失败时, 之前的路由表保持不变
+	err := serv.ReplaceRoutes(func(s *HTTPServer) error {
+		s.Get("/v2/user", handler)
+		return errors.New("plugin failed")
+	})
+	require.Error(t, err)
+	require.Equal(t, []string{"/v1/user"}, patterns(serv.Routes()))
+
+	err = serv.ReplaceRoutes(func(s *HTTPServer) error {
+		s.Get("/v2/user", handler).Name("user")
+		_, err := s.AddRouteE(http.MethodGet, "/v2/order", handler)
+		return err
+	})
+	require.NoError(t, err)
+	require.Equal(t, []string{"/v2/order", "/v2/user"}, patterns(serv.Routes()))
+
+	u, err := serv.URLFor("user")
+	require.NoError(t, err)
+	require.Equal(t, "/v2/user", u)
+
+	resp := httptest.NewRecorder()
+	serv.ServeHTTP(resp, httptest.NewRequest(http.MethodGet, "/v1/user", nil))
+	require.Equal(t, http.StatusNotFound, resp.Code)
+}
+
+// go test -race -v server/*.go -run TestHTTPServer_ConcurrentRouteUpdate
+func TestHTTPServer_ConcurrentRouteUpdate(t *testing.T) {
+
+	handler := func(ctx *Context) {
+		ctx.WriteString(http.StatusOK, []byte(ctx.MatchedPath))
+	}
+
+	serv := New(":8081")
+	serv.Get("/health", handler)
+
+	var wg sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 100; j++ {
+				resp := httptest.NewRecorder()
+				serv.ServeHTTP(resp, httptest.NewRequest(http.MethodGet, "/health", nil))
+				require.Equal(t, http.StatusOK, resp.Code)
+			}
+		}()
+	}
+	for i := 0; i < 50; i++ {
+		path := fmt.Sprintf("/plugin/%d", i)
+		serv.Get(path, handler)
+		if i%2 == 0 {
+			require.NoError(t, serv.RemoveRoute(http.MethodGet, path))
+		}
+	}
+	wg.Wait()
+	require.Len(t, serv.Routes(), 26)
+}
+
+// go test -race -v server/*.go -run TestHTTPServer_ReplaceRoutesConcurrentUpdate
+func TestHTTPServer_ReplaceRoutesConcurrentUpdate(t *testing.T) {
+
+	handler := func(ctx *Context) {
+		ctx.WriteString(http.StatusOK, []byte(ctx.MatchedPath))
+	}
+
+	serv := New(":8081")
+	serv.Get("/health", handler)
+	// 先开始处理请求, ReplaceRoutes 发布的路由表会被请求读取
+	resp := httptest.NewRecorder()
+	serv.ServeHTTP(resp, httptest.NewRequest(http.MethodGet, "/health", nil))
+	require.Equal(t, http.StatusOK, resp.Code)
+
+	// 插件保留了影子 server 上创建的分组和路由
+	var group *RouterGroup
+	var route *Route
+	err := serv.ReplaceRoutes(func(s *HTTPServer) error {
+		s.Get("/health", handler)
+		group = s.Group("/plugin")
+		route = group.Get("/index", handler)
+		return nil
+	})
+	require.NoError(t, err)
+
+	var wg sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 100; j++ {
+				resp := httptest.NewRecorder()
+				serv.ServeHTTP(resp, httptest.NewRequest(http.MethodGet, "/plugin/index", nil))
+				require.Equal(t, http.StatusOK, resp.Code)
+			}
+		}()
+	}
+	for i := 0; i < 50; i++ {
+		path := fmt.Sprintf("/%d", i)
+		group.Get(path, handler)
+		if i%2 == 0 {
+			require.NoError(t, group.RemoveRoute(http.MethodGet, path))
+		}
+	}
+	route.Name("plugin-index")
+	wg.Wait()
+
+	// 发布之后的修改对 serv 可见
+	require.Len(t, serv.Routes(), 27)
+	u, err := serv.URLFor("plugin-index")
+	require.NoError(t, err)
+	require.Equal(t, "/plugin/index", u)
+	resp = httptest.NewRecorder()
+	serv.ServeHTTP(resp, httptest.NewRequest(http.MethodGet, "/plugin/1", nil))
+	require.Equal(t, http.StatusOK, resp.Code)
+}
+
+// go test -v server/*.go -run TestHTTPServer_AddRouteFailedHost
+func TestHTTPServer_AddRouteFailedHost(t *testing.T) {
+	serv := New(":8081")
+	serv.Get("/user", func(ctx *Context) {
+		ctx.WriteString(http.StatusOK, []byte("default"))
+	})
+	// 注册失败时不能留下空的 host 路由树, 否则这个 host 的请求都会 404
+	_, err := serv.Host("api.example.com").AddRouteE("UNKNOWN", "/user", func(ctx *Context) {})
+	require.ErrorIs(t, err, ErrMethodNotSupported)
+	require.ErrorIs(t, serv.Host("api.example.com").RemoveRoute(http.MethodGet, "/user"), ErrRouteNotFound)
+
+	resp := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodGet, "/user", nil)
+	req.Host = "api.example.com"
+	serv.ServeHTTP(resp, req)
+	require.Equal(t, "default", resp.Body.String())
+}
+
+// go test -bench=BenchmarkHTTPServer_AddRoute -benchmem -run=^$ ./server/
+func BenchmarkHTTPServer_AddRoute(b *testing.B) {
+	handler := func(ctx *Context) {}
+	benchCases := []struct {
+		name    string
+		serving bool
+	}{
+		{name: "before serving"},
+		// 开始处理请求之后, 每次注册都会复制路由表
+		{name: "serving", serving: true},
+	}
+	for _, bc := range benchCases {
+		b.Run(bc.name, func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				serv := New(":8081")
+				if bc.serving {
+					serv.ServeHTTP(&discardWriter{header: make(http.Header)}, httptest.NewRequest(http.MethodGet, "/", nil))
+				}
+				for j := 0; j < 1000; j++ {
+					serv.Get(fmt.Sprintf("/api/v1/resource%d/:id", j), handler)
+				}
+			}
+		})
+	}
+}
+
+func patterns(routes []RouteInfo) []string {
+	res := make([]string, 0, len(routes))
+	for _, route := range routes {
+		res = append(res, route.Pattern)
+	}
+	return res
+}
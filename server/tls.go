@@ -0,0 +1,152 @@
+package server
+
+import (
+	"crypto/tls"
+	"log"
+	"net/http"
+	"os"
+	"sync"
+	"time"
+
+	"golang.org/x/net/http2"
+	"golang.org/x/net/http2/h2c"
+)
+
+// WithTLSConfig Start 和 Run 使用 cfg 提供 HTTPS 服务, cfg 需要设置 Certificates 或者 GetCertificate
+func WithTLSConfig(cfg *tls.Config) Option {
+	return func(s *HTTPServer) {
+		s.tlsConfig = cfg
+	}
+}
+
+// WithCertFiles Start 和 Run 使用证书文件提供 HTTPS 和 HTTP/2 服务, 和 StartTLS 一样会自动加载更新后的证书
+// 同时设置了 WithTLSConfig 时, 使用 cfg 中除了证书以外的配置
+func WithCertFiles(certFile string, keyFile string) Option {
+	return func(s *HTTPServer) {
+		s.certFile = certFile
+		s.keyFile = keyFile
+	}
+}
+
+// WithH2C 是否允许不加密的 HTTP/2 (h2c), 适合内部服务之间的调用, 默认关闭
+func WithH2C(enable bool) Option {
+	return func(s *HTTPServer) {
+		s.h2c = enable
+	}
+}
+
+// WithCertReloadInterval StartTLS 和 WithCertFiles 检查证书文件是否更新的间隔, 默认 1 分钟
+func WithCertReloadInterval(interval time.Duration) Option {
+	return func(s *HTTPServer) {
+		s.certReloadInterval = interval
+	}
+}
+
+// StartTLS 使用证书文件提供 HTTPS 和 HTTP/2 服务
+// 证书文件更新之后, 新的连接会使用新的证书, 不需要重启服务
+func (s *HTTPServer) StartTLS(certFile string, keyFile string) error {
+	reloader, err := newCertReloader(certFile, keyFile, s.certReloadInterval)
+	if err != nil {
+		return err
+	}
+	cfg := &tls.Config{}
+	if s.tlsConfig != nil {
+		cfg = s.tlsConfig.Clone()
+	}
+	cfg.Certificates = nil
+	cfg.GetCertificate = reloader.GetCertificate
+	return s.start(cfg)
+}
+
+// handler 返回交给 http.Server 的 handler, 开启 h2c 时支持不加密的 HTTP/2
+func (s *HTTPServer) handler() http.Handler {
+	if s.h2c {
+		return h2c.NewHandler(s, &http2.Server{})
+	}
+	return s
+}
+
+// certReloader 在握手时按间隔检查证书文件的修改时间, 有更新时重新加载
+// 加载失败时继续使用旧的证书
+type certReloader struct {
+	certFile string
+	keyFile  string
+	interval time.Duration
+
+	mu        sync.RWMutex
+	cert      *tls.Certificate
+	modTime   time.Time
+	checkedAt time.Time
+}
+
+func newCertReloader(certFile string, keyFile string, interval time.Duration) (*certReloader, error) {
+	c := &certReloader{
+		certFile: certFile,
+		keyFile:  keyFile,
+		interval: interval,
+	}
+	modTime, err := c.latestModTime()
+	if err != nil {
+		return nil, err
+	}
+	if err = c.load(modTime); err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+// GetCertificate 用于 tls.Config.GetCertificate
+func (c *certReloader) GetCertificate(*tls.ClientHelloInfo) (*tls.Certificate, error) {
+	c.mu.RLock()
+	cert, checkedAt := c.cert, c.checkedAt
+	c.mu.RUnlock()
+	if time.Since(checkedAt) < c.interval {
+		return cert, nil
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	// 其他握手已经检查过了
+	if time.Since(c.checkedAt) < c.interval {
+		return c.cert, nil
+	}
+	c.checkedAt = time.Now()
+	modTime, err := c.latestModTime()
+	if err != nil {
+		log.Printf("stat certificate failed: %v\n", err)
+		return c.cert, nil
+	}
+	if modTime.After(c.modTime) {
+		if err = c.load(modTime); err != nil {
+			log.Printf("reload certificate failed: %v\n", err)
+		}
+	}
+	return c.cert, nil
+}
+
+// load 加载证书, 调用方需要持有写锁
+func (c *certReloader) load(modTime time.Time) error {
+	cert, err := tls.LoadX509KeyPair(c.certFile, c.keyFile)
+	if err != nil {
+		return err
+	}
+	c.cert = &cert
+	c.modTime = modTime
+	c.checkedAt = time.Now()
+	return nil
+}
+
+// latestModTime 证书和私钥中较新的修改时间
+func (c *certReloader) latestModTime() (time.Time, error) {
+	var latest time.Time
+	for _, file := range []string{c.certFile, c.keyFile} {
+		info, err := os.Stat(file)
+		if err != nil {
+			return time.Time{}, err
+		}
+		if info.ModTime().After(latest) {
+			latest = info.ModTime()
+		}
+	}
+	return latest, nil
+}
@@ -0,0 +1,187 @@
+package server
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"golang.org/x/net/http2"
+)
+
+// go test -v server/*.go -run TestHTTPServer_StartTLS
+func TestHTTPServer_StartTLS(t *testing.T) {
+	dir := t.TempDir()
+	certFile := filepath.Join(dir, "cert.pem")
+	keyFile := filepath.Join(dir, "key.pem")
+	writeSelfSignedCert(t, certFile, keyFile, "first")
+
+	serv := New("127.0.0.1:0", WithCertReloadInterval(0))
+	serv.Get("/proto", func(ctx *Context) {
+		ctx.WriteString(http.StatusOK, []byte(ctx.Req.Proto))
+	})
+	require.NoError(t, serv.StartTLS(certFile, keyFile))
+	defer func() {
+		require.NoError(t, serv.Shutdown(context.Background()))
+	}()
+
+	get := func() (*http.Response, string) {
+		client := &http.Client{
+			Transport: &http.Transport{
+				TLSClientConfig:   &tls.Config{InsecureSkipVerify: true},
+				ForceAttemptHTTP2: true,
+			},
+		}
+		resp, err := client.Get("https://" + serv.Addr() + "/proto")
+		require.NoError(t, err)
+		defer resp.Body.Close()
+		body := make([]byte, 64)
+		n, _ := resp.Body.Read(body)
+		return resp, string(body[:n])
+	}
+
+	resp, body := get()
+	require.Equal(t, "HTTP/2.0", body)
+	require.Equal(t, "first", resp.TLS.PeerCertificates[0].Subject.CommonName)
+
+	// 证书轮换之后, 新的连接使用新的证书
+	writeSelfSignedCert(t, certFile, keyFile, "second")
+	future := time.Now().Add(time.Minute)
+	require.NoError(t, os.Chtimes(certFile, future, future))
+	resp, _ = get()
+	require.Equal(t, "second", resp.TLS.PeerCertificates[0].Subject.CommonName)
+
+	// 证书文件损坏时继续使用旧的证书
+	require.NoError(t, os.WriteFile(certFile, []byte("broken"), 0o600))
+	future = future.Add(time.Minute)
+	require.NoError(t, os.Chtimes(certFile, future, future))
+	resp, _ = get()
+	require.Equal(t, "second", resp.TLS.PeerCertificates[0].Subject.CommonName)
+}
+
+// go test -v server/*.go -run TestHTTPServer_StartTLSError
+func TestHTTPServer_StartTLSError(t *testing.T) {
+	serv := New("127.0.0.1:0")
+	require.Error(t, serv.StartTLS("not-exists.pem", "not-exists.key"))
+}
+
+// go test -v server/*.go -run TestHTTPServer_RunWithCertFiles
+func TestHTTPServer_RunWithCertFiles(t *testing.T) {
+	dir := t.TempDir()
+	certFile := filepath.Join(dir, "cert.pem")
+	keyFile := filepath.Join(dir, "key.pem")
+	writeSelfSignedCert(t, certFile, keyFile, "first")
+
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	serv := New(l.Addr().String(), WithListener(l), WithCertFiles(certFile, keyFile), WithCertReloadInterval(0))
+	serv.Get("/proto", func(ctx *Context) {
+		ctx.WriteString(http.StatusOK, []byte(ctx.Req.Proto))
+	})
+	ctx, cancel := context.WithCancel(context.Background())
+	errCh := make(chan error, 1)
+	go func() {
+		errCh <- serv.Run(ctx)
+	}()
+
+	get := func() *http.Response {
+		client := &http.Client{
+			Transport: &http.Transport{
+				TLSClientConfig:   &tls.Config{InsecureSkipVerify: true},
+				ForceAttemptHTTP2: true,
+			},
+		}
+		var resp *http.Response
+		// Run 在后台启动, 等到开始处理请求
+		require.Eventually(t, func() bool {
+			var err error
+			resp, err = client.Get("https://" + l.Addr().String() + "/proto")
+			return err == nil
+		}, time.Second*5, time.Millisecond*10)
+		defer resp.Body.Close()
+		return resp
+	}
+
+	resp := get()
+	require.Equal(t, 2, resp.ProtoMajor)
+	require.Equal(t, "first", resp.TLS.PeerCertificates[0].Subject.CommonName)
+
+	// Run 启动的服务同样会加载更新后的证书
+	writeSelfSignedCert(t, certFile, keyFile, "second")
+	future := time.Now().Add(time.Minute)
+	require.NoError(t, os.Chtimes(certFile, future, future))
+	resp = get()
+	require.Equal(t, "second", resp.TLS.PeerCertificates[0].Subject.CommonName)
+
+	cancel()
+	require.NoError(t, <-errCh)
+}
+
+// go test -v server/*.go -run TestHTTPServer_RunCertFilesError
+func TestHTTPServer_RunCertFilesError(t *testing.T) {
+	serv := New("127.0.0.1:0", WithCertFiles("not-exists.pem", "not-exists.key"))
+	require.Error(t, serv.Run(context.Background()))
+}
+
+// go test -v server/*.go -run TestHTTPServer_H2C
+func TestHTTPServer_H2C(t *testing.T) {
+	serv := New("127.0.0.1:0", WithH2C(true))
+	serv.Get("/proto", func(ctx *Context) {
+		ctx.WriteString(http.StatusOK, []byte(ctx.Req.Proto))
+	})
+	require.NoError(t, serv.Start())
+	defer func() {
+		require.NoError(t, serv.Shutdown(context.Background()))
+	}()
+
+	client := &http.Client{
+		Transport: &http2.Transport{
+			AllowHTTP: true,
+			DialTLSContext: func(ctx context.Context, network, addr string, cfg *tls.Config) (net.Conn, error) {
+				var d net.Dialer
+				return d.DialContext(ctx, network, addr)
+			},
+		},
+	}
+	resp, err := client.Get("http://" + serv.Addr() + "/proto")
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	require.Equal(t, 2, resp.ProtoMajor)
+
+	// HTTP/1.1 仍然可用
+	resp, err = http.Get("http://" + serv.Addr() + "/proto")
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	require.Equal(t, 1, resp.ProtoMajor)
+}
+
+func writeSelfSignedCert(t *testing.T, certFile string, keyFile string, commonName string) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	tpl := &x509.Certificate{
+		SerialNumber: big.NewInt(time.Now().UnixNano()),
+		Subject:      pkix.Name{CommonName: commonName},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		IPAddresses:  []net.IP{net.ParseIP("127.0.0.1")},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tpl, tpl, &key.PublicKey, key)
+	require.NoError(t, err)
+	keyDer, err := x509.MarshalECPrivateKey(key)
+	require.NoError(t, err)
+
+	require.NoError(t, os.WriteFile(certFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), 0o600))
+	require.NoError(t, os.WriteFile(keyFile, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDer}), 0o600))
+}
@@ -0,0 +1,272 @@
+package server
+
+import (
+	"fmt"
+	"net/http"
+	"net/mail"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"unicode/utf8"
+)
+
+// ValidateFunc 校验字段的值, param 为规则中 = 之后的参数, 例如 min=1 中的 1
+// 指针字段传入的是指针指向的值, nil 指针传入指向类型的零值
+type ValidateFunc func(val reflect.Value, param string) bool
+
+// ValidationErrors 校验失败的字段, 字段名 -> 规则名 -> 错误提示
+// 字段名优先使用 path, query, header, form 的参数名, 然后是 json tag, 最后是结构体字段名
+type ValidationErrors map[string]map[string]string
+
+func (errs ValidationErrors) Error() string {
+	fields := make([]string, 0, len(errs))
+	for field := range errs {
+		fields = append(fields, field)
+	}
+	sort.Strings(fields)
+	var sb strings.Builder
+	sb.WriteString("validation failed:")
+	for _, field := range fields {
+		rules := make([]string, 0, len(errs[field]))
+		for rule := range errs[field] {
+			rules = append(rules, rule)
+		}
+		sort.Strings(rules)
+		for _, rule := range rules {
+			sb.WriteString(" ")
+			sb.WriteString(field)
+			sb.WriteString(" ")
+			sb.WriteString(errs[field][rule])
+			sb.WriteString(";")
+		}
+	}
+	return strings.TrimSuffix(sb.String(), ";")
+}
+
+func (errs ValidationErrors) add(field string, rule string, msg string) {
+	if errs[field] == nil {
+		errs[field] = make(map[string]string)
+	}
+	errs[field][rule] = msg
+}
+
+// ValidationFormatter 把校验错误转换成响应的状态码和响应体, 响应体按 JSON 返回
+type ValidationFormatter func(errs ValidationErrors) (status int, body any)
+
+func defaultValidationFormatter(errs ValidationErrors) (int, any) {
+	return http.StatusUnprocessableEntity, map[string]any{"errors": errs}
+}
+
+// validationRule message 返回校验失败时的提示
+type validationRule struct {
+	validate ValidateFunc
+	message  func(val reflect.Value, param string) string
+}
+
+// validationTag validate tag 中的一条规则
+type validationTag struct {
+	name  string
+	param string
+}
+
+func parseValidationTag(tag string) []validationTag {
+	if tag == "" || tag == "-" {
+		return nil
+	}
+	parts := strings.Split(tag, ",")
+	tags := make([]validationTag, 0, len(parts))
+	for _, part := range parts {
+		name, param, _ := strings.Cut(strings.TrimSpace(part), "=")
+		if name != "" {
+			tags = append(tags, validationTag{name: name, param: param})
+		}
+	}
+	return tags
+}
+
+type validator struct {
+	mu        sync.RWMutex
+	rules     map[string]*validationRule
+	formatter ValidationFormatter
+}
+
+// defaultValidator 不通过 HTTPServer 创建的 ctx 使用内置的规则
+var defaultValidator = newValidator()
+
+func newValidator() *validator {
+	return &validator{
+		rules: map[string]*validationRule{
+			"min":   {validate: validateMin, message: sizeMessage("at least")},
+			"max":   {validate: validateMax, message: sizeMessage("at most")},
+			"len":   {validate: validateLen, message: sizeMessage("exactly")},
+			"email": {validate: validateEmail, message: staticMessage("must be a valid email address")},
+			"oneof": {validate: validateOneOf, message: staticMessage("must be one of [{param}]")},
+		},
+		formatter: defaultValidationFormatter,
+	}
+}
+
+func (v *validator) register(name string, fn ValidateFunc, message string) {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	v.rules[name] = &validationRule{validate: fn, message: staticMessage(message)}
+}
+
+func (v *validator) rule(name string) (*validationRule, bool) {
+	v.mu.RLock()
+	defer v.mu.RUnlock()
+	rule, ok := v.rules[name]
+	return rule, ok
+}
+
+// validate 字段为零值时同样校验所有规则, 只有 omitempty 会跳过之后的规则, 例如 validate:"omitempty,email"
+// 规则名在校验之前统一检查, 不会因为字段为零值而漏掉
+func (v *validator) validate(rv reflect.Value, fields []*bindField) error {
+	for _, field := range fields {
+		for _, tag := range field.rules {
+			if tag.name == "required" || tag.name == "omitempty" {
+				continue
+			}
+			if _, ok := v.rule(tag.name); !ok {
+				return fmt.Errorf("%w: %s on field %s", ErrUnknownValidationRule, tag.name, field.name)
+			}
+		}
+	}
+
+	errs := ValidationErrors{}
+	for _, field := range fields {
+		if len(field.rules) == 0 {
+			continue
+		}
+		fv := rv.FieldByIndex(field.index)
+		present := hasValue(fv)
+		// nil 指针按指向类型的零值校验
+		for fv.Kind() == reflect.Pointer {
+			if fv.IsNil() {
+				fv = reflect.Zero(fv.Type().Elem())
+				continue
+			}
+			fv = fv.Elem()
+		}
+	rules:
+		for _, tag := range field.rules {
+			switch tag.name {
+			case "required":
+				if !present {
+					errs.add(field.label, tag.name, "is required")
+					break rules
+				}
+			case "omitempty":
+				if !present {
+					break rules
+				}
+			default:
+				rule, _ := v.rule(tag.name)
+				if !rule.validate(fv, tag.param) {
+					errs.add(field.label, tag.name, rule.message(fv, tag.param))
+				}
+			}
+		}
+	}
+	if len(errs) > 0 {
+		return errs
+	}
+	return nil
+}
+
+func hasValue(fv reflect.Value) bool {
+	switch fv.Kind() {
+	case reflect.Slice, reflect.Map:
+		return fv.Len() > 0
+	}
+	return !fv.IsZero()
+}
+
+func staticMessage(msg string) func(val reflect.Value, param string) string {
+	return func(val reflect.Value, param string) string {
+		return strings.ReplaceAll(msg, "{param}", param)
+	}
+}
+
+// sizeMessage 数字比较值的大小, 字符串, 切片和 map 比较长度
+func sizeMessage(cmp string) func(val reflect.Value, param string) string {
+	return func(val reflect.Value, param string) string {
+		switch val.Kind() {
+		case reflect.String, reflect.Slice, reflect.Map, reflect.Array:
+			return "length must be " + cmp + " " + param
+		}
+		return "must be " + cmp + " " + param
+	}
+}
+
+// compareSize 返回 值 (或长度) 和 param 的比较结果, param 不是数字或者类型不支持时 ok 为 false
+func compareSize(val reflect.Value, param string) (cmp int, ok bool) {
+	limit, err := strconv.ParseFloat(param, 64)
+	if err != nil {
+		return 0, false
+	}
+	var size float64
+	switch val.Kind() {
+	case reflect.String:
+		size = float64(utf8.RuneCountInString(val.String()))
+	case reflect.Slice, reflect.Map, reflect.Array:
+		size = float64(val.Len())
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		size = float64(val.Int())
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		size = float64(val.Uint())
+	case reflect.Float32, reflect.Float64:
+		size = val.Float()
+	default:
+		return 0, false
+	}
+	switch {
+	case size < limit:
+		return -1, true
+	case size > limit:
+		return 1, true
+	}
+	return 0, true
+}
+
+func validateMin(val reflect.Value, param string) bool {
+	cmp, ok := compareSize(val, param)
+	return ok && cmp >= 0
+}
+
+func validateMax(val reflect.Value, param string) bool {
+	cmp, ok := compareSize(val, param)
+	return ok && cmp <= 0
+}
+
+func validateLen(val reflect.Value, param string) bool {
+	cmp, ok := compareSize(val, param)
+	return ok && cmp == 0
+}
+
+func validateEmail(val reflect.Value, param string) bool {
+	if val.Kind() != reflect.String {
+		return false
+	}
+	addr, err := mail.ParseAddress(val.String())
+	return err == nil && addr.Address == val.String()
+}
+
+// validateOneOf 可选值用空格分隔, 例如 oneof=asc desc
+func validateOneOf(val reflect.Value, param string) bool {
+	s := fmt.Sprint(val.Interface())
+	for _, option := range strings.Fields(param) {
+		if s == option {
+			return true
+		}
+	}
+	return false
+}
+
+// RegisterValidation 注册自定义的校验规则, 和内置规则同名时覆盖内置规则
+// message 为校验失败时的提示, 其中的 {param} 会替换成规则的参数
+func (s *HTTPServer) RegisterValidation(name string, fn ValidateFunc, message string) {
+	s.validator.register(name, fn, message)
+}
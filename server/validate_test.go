@@ -0,0 +1,236 @@
+package server
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+type validateUserReq struct {
+	ID      int      `path:"id" validate:"required,min=1"`
+	Name    string   `json:"name" validate:"required,max=8"`
+	Email   string   `json:"email" validate:"omitempty,email"`
+	Sort    string   `query:"sort" default:"asc" validate:"oneof=asc desc"`
+	Tags    []string `json:"tags" validate:"max=2"`
+	Nick    *string  `json:"nick" validate:"omitempty,len=4"`
+	Phone   string   `json:"phone" validate:"omitempty,mobile"`
+	Address struct {
+		City string `json:"city" validate:"required"`
+	} `json:"address"`
+}
+
+// go test -v server/*.go -run TestContext_BindValidate
+func TestContext_BindValidate(t *testing.T) {
+	testCases := []struct {
+		name    string
+		path    string
+		body    string
+		wantErr ValidationErrors
+	}{
+		{
+			name: "valid",
+			path: "/user/1?sort=desc",
+			body: `{"name":"jungle","email":"jungle@example.com","tags":["a"],"nick":"jojo","phone":"13800138000","address":{"city":"gz"}}`,
+		},
+		{
+			name: "optional fields are skipped when empty",
+			path: "/user/1",
+			body: `{"name":"jungle","address":{"city":"gz"}}`,
+		},
+		{
+			name: "zero values are validated without omitempty",
+			path: "/user/1?sort=",
+			body: `{"name":"jungle","address":{"city":"gz"}}`,
+			wantErr: ValidationErrors{
+				"sort": {"oneof": "must be one of [asc desc]"},
+			},
+		},
+		{
+			name: "invalid",
+			path: "/user/0?sort=random",
+			body: `{"name":"jungle-jungle","email":"jungle","tags":["a","b","c"],"nick":"","phone":"110"}`,
+			wantErr: ValidationErrors{
+				"id":           {"required": "is required"},
+				"name":         {"max": "length must be at most 8"},
+				"email":        {"email": "must be a valid email address"},
+				"sort":         {"oneof": "must be one of [asc desc]"},
+				"tags":         {"max": "length must be at most 2"},
+				"nick":         {"len": "length must be exactly 4"},
+				"phone":        {"mobile": "must be a mobile number"},
+				"address.city": {"required": "is required"},
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			var err error
+			serv := New(":8081")
+			serv.RegisterValidation("mobile", func(val reflect.Value, param string) bool {
+				return val.Kind() == reflect.String && len(val.String()) == 11 && strings.HasPrefix(val.String(), "1")
+			}, "must be a mobile number")
+			serv.Post("/user/:id", func(ctx *Context) {
+				err = ctx.Bind(&validateUserReq{})
+			})
+
+			req := httptest.NewRequest(http.MethodPost, tc.path, strings.NewReader(tc.body))
+			req.Header.Set("Content-Type", "application/json")
+			serv.ServeHTTP(httptest.NewRecorder(), req)
+			if tc.wantErr == nil {
+				require.NoError(t, err)
+				return
+			}
+			require.Equal(t, tc.wantErr, err)
+		})
+	}
+}
+
+// go test -v server/*.go -run TestContext_MustBind
+func TestContext_MustBind(t *testing.T) {
+	type createReq struct {
+		Page int    `query:"page"`
+		Name string `json:"name" validate:"required,min=2"`
+	}
+
+	testCases := []struct {
+		name     string
+		opts     []Option
+		path     string
+		body     string
+		wantCode int
+		wantBody string
+	}{
+		{
+			name:     "ok",
+			path:     "/user",
+			body:     `{"name":"jungle"}`,
+			wantCode: http.StatusOK,
+			wantBody: "ok",
+		},
+		{
+			name:     "validation errors",
+			path:     "/user",
+			body:     `{"name":"j"}`,
+			wantCode: http.StatusUnprocessableEntity,
+			wantBody: `{"errors":{"name":{"min":"length must be at least 2"}}}`,
+		},
+		{
+			name: "custom formatter",
+			opts: []Option{WithValidationFormatter(func(errs ValidationErrors) (int, any) {
+				return http.StatusBadRequest, map[string]any{"code": 40001, "msg": errs.Error()}
+			})},
+			path:     "/user",
+			body:     `{}`,
+			wantCode: http.StatusBadRequest,
+			wantBody: `{"code":40001,"msg":"validation failed: name is required"}`,
+		},
+		{
+			name:     "bind error",
+			path:     "/user?page=first",
+			body:     `{"name":"jungle"}`,
+			wantCode: http.StatusBadRequest,
+			wantBody: `{"error":"bind query \"page\"=\"first\" to field Page: strconv.ParseInt: parsing \"first\": invalid syntax"}`,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			serv := New(":8081", tc.opts...)
+			serv.Post("/user", func(ctx *Context) {
+				var req createReq
+				if !ctx.MustBind(&req) {
+					return
+				}
+				ctx.WriteString(http.StatusOK, []byte("ok"))
+			})
+
+			req := httptest.NewRequest(http.MethodPost, tc.path, strings.NewReader(tc.body))
+			req.Header.Set("Content-Type", "application/json")
+			resp := httptest.NewRecorder()
+			serv.ServeHTTP(resp, req)
+			require.Equal(t, tc.wantCode, resp.Code)
+			require.Equal(t, tc.wantBody, resp.Body.String())
+		})
+	}
+}
+
+// go test -v server/*.go -run TestValidationErrors_Error
+func TestValidationErrors_Error(t *testing.T) {
+	errs := ValidationErrors{
+		"page": {"min": "must be at least 1", "max": "must be at most 100"},
+		"name": {"required": "is required"},
+	}
+	require.EqualError(t, errs, "validation failed: name is required; page must be at most 100; page must be at least 1")
+}
+
+// go test -v server/*.go -run TestContext_BindUnknownRule
+func TestContext_BindUnknownRule(t *testing.T) {
+	type req struct {
+		Name string `query:"name" validate:"unknown"`
+	}
+	ctx := NewContext(httptest.NewRequest(http.MethodGet, "/?name=jungle", nil), httptest.NewRecorder(), nil)
+	require.ErrorIs(t, ctx.Bind(&req{}), ErrUnknownValidationRule)
+
+	// 字段为零值时也会检查规则名
+	type optionalReq struct {
+		Name string `query:"name" validate:"omitempty,unknown"`
+	}
+	ctx = NewContext(httptest.NewRequest(http.MethodGet, "/", nil), httptest.NewRecorder(), nil)
+	require.ErrorIs(t, ctx.Bind(&optionalReq{}), ErrUnknownValidationRule)
+}
+
+// go test -v server/*.go -run TestContext_BindValidateZeroValue
+func TestContext_BindValidateZeroValue(t *testing.T) {
+	type listReq struct {
+		Page  int     `query:"page" validate:"min=1"`
+		Role  string  `query:"role" validate:"oneof=admin user"`
+		Size  *int    `query:"size" validate:"min=1"`
+		Email string  `query:"email" validate:"omitempty,email"`
+		Limit *int    `query:"limit" validate:"omitempty,max=100"`
+		Nick  *string `query:"nick" validate:"omitempty,len=4"`
+	}
+
+	testCases := []struct {
+		name    string
+		path    string
+		wantErr ValidationErrors
+	}{
+		{
+			name: "zero values",
+			path: "/?page=0",
+			wantErr: ValidationErrors{
+				"page": {"min": "must be at least 1"},
+				"role": {"oneof": "must be one of [admin user]"},
+				"size": {"min": "must be at least 1"},
+			},
+		},
+		{
+			name: "valid",
+			path: "/?page=1&role=user&size=10",
+		},
+		{
+			name: "omitempty validates present values",
+			path: "/?page=1&role=user&size=10&email=jungle&limit=200",
+			wantErr: ValidationErrors{
+				"email": {"email": "must be a valid email address"},
+				"limit": {"max": "must be at most 100"},
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctx := NewContext(httptest.NewRequest(http.MethodGet, tc.path, nil), httptest.NewRecorder(), nil)
+			err := ctx.Bind(&listReq{})
+			if tc.wantErr == nil {
+				require.NoError(t, err)
+				return
+			}
+			require.Equal(t, tc.wantErr, err)
+		})
+	}
+}